@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/ipfs/go-ds-bench/master"
 	"github.com/ipfs/go-ds-bench/options"
@@ -16,28 +17,157 @@ func assert(err error) {
 	}
 }
 
+const usage = `Usage: master [-continue] [-plot-format png,svg,pdf,html] [-baseline datastore | -baseline-run results.json] [command]
+
+Without a command, runs benchmarks and plots results.
+
+Commands:
+  plot    plot results.json without running anything
+  export  write results as csv, json or benchstat input, see export -h
+  compare compare two sets of results and flag regressions, see compare -h
+`
+
 func main() {
 	cont := flag.Bool("continue", false, "Continue previous work")
+	formats := flag.String("plot-format", "png", "Comma separated plot formats: png, svg, pdf, html (interactive)")
+	baseline := flag.String("baseline", "", "Add speedup plots normalized to this datastore, like flatfs-none")
+	baselineRun := flag.String("baseline-run", "", "Add speedup plots normalized to each datastore's results in this results.json")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
-	if flag.NArg() != 0 {
-		fmt.Println("Usage: master [-continue]")
-		return
+	if err := master.SetPlotFormats(strings.Split(*formats, ",")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch {
+	case *baseline != "" && *baselineRun != "":
+		fmt.Fprintln(os.Stderr, "-baseline and -baseline-run are exclusive")
+		flag.Usage()
+		os.Exit(2)
+	case *baseline != "":
+		master.SetBaselineDatastore(*baseline)
+	case *baselineRun != "":
+		run, err := master.LoadBatch(*baselineRun)
+		assert(err)
+		master.SetBaselineRun(run)
 	}
 
-	b, err := master.BuildBatch(newSpec, *cont)
+	switch flag.Arg(0) {
+	case "":
+		b, err := master.BuildBatch(newSpec, *cont)
+		if err != nil {
+			panic(err)
+		}
+
+		if err := b.Start(); err != nil {
+			panic(err)
+		}
+	case "plot":
+		b, err := master.LoadBatch("results.json")
+		assert(err)
+		assert(b.Plot())
+	case "export":
+		export(flag.Args()[1:])
+	case "compare":
+		compare(flag.Args()[1:])
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func export(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "csv", "Export format: "+strings.Join(master.ExportFormats, ", "))
+	out := fs.String("o", "", "Output file, stdout if empty")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: master export [-format csv|json|bench] [-o file] [results.json]")
+		fs.PrintDefaults()
+	}
+	assert(fs.Parse(args))
+
+	known := false
+	for _, f := range master.ExportFormats {
+		known = known || f == *format
+	}
+	if !known {
+		fmt.Fprintf(os.Stderr, "unknown export format '%s'\n", *format)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	path := "results.json"
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	b, err := master.LoadBatch(path)
+	assert(err)
+
+	w := os.Stdout
+	if *out != "" {
+		w, err = os.Create(*out)
+		assert(err)
+		defer w.Close()
+	}
+	assert(b.Export(w, *format))
+}
+
+func compare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	threshold := fs.Float64("threshold", 0, "Regression threshold in percent, exit with 1 if a metric got worse by more, 0 disables")
+	metrics := fs.String("metric", "ns/op", "Comma separated metrics checked for regressions: "+strings.Join(master.MetricNames(), ", "))
+	alpha := fs.Float64("alpha", 0.05, "Significance level, changes with a higher p-value aren't regressions. Points with too few samples to reach it (less than 4+4 at 0.05) are marked untested and only checked against -threshold")
+	plots := fs.String("plots", "x_compare", "Directory comparison plots are written to, none if empty")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: master compare [flags] old.json[,old2.json...] new.json[,new2.json...]")
+		fmt.Fprintln(fs.Output(), "Results of more files on a side are samples of the same points.")
+		fs.PrintDefaults()
+	}
+	assert(fs.Parse(args))
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	load := func(paths string) []*master.BatchSpec {
+		var out []*master.BatchSpec
+		for _, path := range strings.Split(paths, ",") {
+			b, err := master.LoadBatch(path)
+			assert(err)
+			out = append(out, b)
+		}
+		return out
+	}
+	base, head := load(fs.Arg(0)), load(fs.Arg(1))
+
+	regressions, err := master.Compare(os.Stdout, base, head, master.CompareOptions{
+		Metrics:   strings.Split(*metrics, ","),
+		Threshold: *threshold,
+		Alpha:     *alpha,
+		PlotDir:   *plots,
+	})
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
 
-	if err := b.Start(); err != nil {
-		panic(err)
+	if len(regressions) > 0 {
+		fmt.Fprintf(os.Stderr, "%d regressions over %g%%:\n", len(regressions), *threshold)
+		for _, r := range regressions {
+			fmt.Fprintln(os.Stderr, r)
+		}
+		os.Exit(1)
 	}
 }
 
 func newSpec() (*master.BatchSpec, error) {
 	// Job matrix - systems x filesystems x datastores x series
-	// datastores := []string{"flatfs", "badger", "leveldb", "bolt"}
+	// datastores := []string{"flatfs", "badger", "leveldb", "bolt", "pebble"}
 	datastores := []string{"flatfs", "leveldb", "rados"}
 	// filesystems := []fs{fsBtrfs, fsExt4, fsExt3, fsNtfs, fsJfs, fsXfs}
 	filesystems := []fs{nofs}
@@ -46,14 +176,43 @@ func newSpec() (*master.BatchSpec, error) {
 			master.BenchBasicAddBatch(),
 			master.BenchBasicAdd(),
 			master.BenchBasicGet(),
+			master.BenchBasicGetSize(),
 			master.BenchBasicHas(),
+			master.BenchBasicUpdate(),
+			master.BenchBasicUpdateBatch(),
 
 			master.BenchBSizingAddBatch(),
 			master.BenchBSizingAdd(),
 			master.BenchBSizingGet(),
+			master.BenchBSizingGetSize(),
 			master.BenchBSizingHas(),
+			master.BenchBSizingUpdate(),
+			master.BenchBSizingUpdateBatch(),
+
+			master.BenchHitRatioGet(),
+			master.BenchHitRatioGetSize(),
+			master.BenchHitRatioHas(),
+
+			master.BenchResizeUpdate(),
+			master.BenchResizeUpdateBatch(),
 
 			master.BenchAddBatch(),
+
+			master.BenchFlatfsShardingGet(),
+			master.BenchFlatfsShardingAdd(),
+			master.BenchFlatfsCidShardingGet(),
+			master.BenchLeveldbBlockCacheGet(),
+			master.BenchLeveldbWriteBufferAddBatch(),
+			master.BenchLeveldbBloomHas(),
+
+			master.BenchTxnReadModifyWrite(),
+			master.BenchTxnPut(),
+			master.BenchTxnConflicts(),
+
+			master.BenchReopen(),
+			master.BenchReopenUnclean(),
+			master.BenchCrash(),
+			master.BenchVerifiedGet(),
 		}
 	}
 
@@ -88,10 +247,7 @@ func newSpec() (*master.BatchSpec, error) {
 				Name:    fmt.Sprintf("%s-%s", ds, fs.Name),
 				Scripts: fs.Scripts,
 				Tags:    []string{fs.Name},
-				Params: map[string]interface{}{
-					"Sync":    true,
-					"DataDir": "MDIR",
-				},
+				Params:  dsParams(ds),
 			})
 		}
 	}
@@ -99,6 +255,25 @@ func newSpec() (*master.BatchSpec, error) {
 	return s, nil
 }
 
+// dsParams returns WorkerDatastore.Params for a datastore type, run
+// `./worker.test -list-candidates` for all accepted params
+func dsParams(ds string) map[string]interface{} {
+	switch ds {
+	case "memory-map":
+		return map[string]interface{}{}
+	case "rados":
+		return map[string]interface{}{
+			"CephConfig": "/etc/ceph/ceph.conf",
+			"CephPool":   "bench",
+		}
+	default:
+		return map[string]interface{}{
+			"SyncMode": options.SyncPerOp,
+			"DataDir":  "MDIR",
+		}
+	}
+}
+
 type fs struct {
 	Name    string
 	Scripts struct {
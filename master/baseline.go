@@ -0,0 +1,173 @@
+package master
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gonum/stat"
+
+	"golang.org/x/tools/benchmark/parse"
+	"gonum.org/v1/plot"
+)
+
+// plotBaseline selects results speedup plots are normalized to, see
+// SetBaselineDatastore and SetBaselineRun
+var plotBaseline struct {
+	datastore string
+	run       *BatchSpec
+	name      string
+}
+
+// SetBaselineDatastore adds speedup plots, normalizing ns/op of every
+// datastore to the datastore named ds, like flatfs-none
+func SetBaselineDatastore(ds string) {
+	plotBaseline.datastore, plotBaseline.run = ds, nil
+	plotBaseline.name = ds
+}
+
+// SetBaselineRun adds speedup plots, normalizing ns/op of every datastore to
+// its own results in run
+func SetBaselineRun(run *BatchSpec) {
+	plotBaseline.datastore, plotBaseline.run = "", run
+	plotBaseline.name = "baseline-run"
+}
+
+// baseline are results a series' results are normalized to
+type baseline struct {
+	name string
+	// of returns baseline results of a datastore, nil if there are none
+	of func(ds string) map[string][]*parse.Benchmark
+	// dsOf maps results of averaged plots to their datastore, see avg
+	dsOf map[*parse.Benchmark]string
+}
+
+// baseline returns the baseline of a series on an instance type, nil if none
+// was selected
+func (b *BatchSpec) baseline(itype string, s *Series) (*baseline, error) {
+	switch {
+	case plotBaseline.datastore != "":
+		if !hasDatastore(b.Datastores, plotBaseline.datastore) {
+			if _, ok := s.Results[plotBaseline.datastore]; !ok {
+				return nil, fmt.Errorf("unknown baseline datastore '%s'", plotBaseline.datastore)
+			}
+		}
+		res := convertFlat(s.Results)[plotBaseline.datastore]
+		return &baseline{
+			name: plotBaseline.datastore,
+			of: func(string) map[string][]*parse.Benchmark {
+				return res
+			},
+		}, nil
+	case plotBaseline.run != nil:
+		var res map[string]map[string][]*parse.Benchmark
+		for _, rs := range plotBaseline.run.Jobs[itype] {
+			if rs.PlotName == s.PlotName {
+				res = convertFlat(rs.Results)
+			}
+		}
+		return &baseline{
+			name: plotBaseline.name,
+			of: func(ds string) map[string][]*parse.Benchmark {
+				return res[ds]
+			},
+		}, nil
+	}
+	return nil, nil
+}
+
+// avg returns the baseline of plots averaging groups of datastores with
+// doAvg, results are normalized to the baseline of their own datastore
+// before being averaged
+func (base *baseline) avg(groups map[string]map[string]map[string]*parse.Benchmark) *baseline {
+	if base == nil {
+		return nil
+	}
+	dsOf := map[*parse.Benchmark]string{}
+	for _, items := range groups {
+		for ds, res := range items {
+			for _, bench := range res {
+				dsOf[bench] = ds
+			}
+		}
+	}
+	return &baseline{name: base.name, of: base.of, dsOf: dsOf}
+}
+
+// normalize returns results of points with a baseline, and a metric of their
+// speedup: mean baseline ns/op over a result's ns/op, above 1 when faster
+func (base *baseline) normalize(results map[string]map[string][]*parse.Benchmark) (map[string]map[string][]*parse.Benchmark, *ysel) {
+	speedup := map[*parse.Benchmark]float64{}
+	out := map[string]map[string][]*parse.Benchmark{}
+	for group, res := range results {
+		for desc, benches := range res {
+			for _, bench := range benches {
+				if bench == nil || bench.NsPerOp <= 0 {
+					continue
+				}
+				ds, ok := base.dsOf[bench]
+				if !ok {
+					ds = group
+				}
+				mean := baseMean(base.of(ds)[desc])
+				if mean <= 0 {
+					continue
+				}
+
+				speedup[bench] = mean / bench.NsPerOp
+				if out[group] == nil {
+					out[group] = map[string][]*parse.Benchmark{}
+				}
+				out[group][desc] = append(out[group][desc], bench)
+			}
+		}
+	}
+
+	return out, &ysel{
+		name: "speedup-vs-" + base.name,
+		sel: func(b *parse.Benchmark) float64 {
+			return speedup[b]
+		},
+	}
+}
+
+// baseMean returns the mean ns/op of baseline results, 0 if none measured it
+func baseMean(benches []*parse.Benchmark) float64 {
+	var ns []float64
+	for _, bench := range benches {
+		if bench != nil && bench.Measured&parse.NsPerOp != 0 {
+			ns = append(ns, bench.NsPerOp)
+		}
+	}
+	if len(ns) == 0 {
+		return 0
+	}
+	return stat.Mean(ns, nil)
+}
+
+// RatioTicks marks powers of 2 of ratios, like 0.5x, 1x, 2x, or default
+// ticks when the range is too narrow for them
+type RatioTicks struct{}
+
+var _ plot.Ticker = RatioTicks{}
+
+// Ticks returns Ticks in a specified range
+func (RatioTicks) Ticks(min, max float64) []plot.Tick {
+	var ticks []plot.Tick
+	if min > 0 {
+		for e := math.Floor(math.Log2(min)); e <= math.Ceil(math.Log2(max)); e++ {
+			v := math.Pow(2, e)
+			ticks = append(ticks, plot.Tick{Value: v, Label: fmt.Sprintf("%gx", v)})
+		}
+	}
+	if len(ticks) > 3 {
+		return ticks
+	}
+
+	ticks = plot.DefaultTicks{}.Ticks(min, max)
+	for i := range ticks {
+		if ticks[i].Label != "" {
+			ticks[i].Label += "x"
+		}
+	}
+	return ticks
+}
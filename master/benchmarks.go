@@ -5,17 +5,67 @@ import (
 	"golang.org/x/tools/benchmark/parse"
 )
 
-var LargeBlockOpts = options.OptionsRange2pow( // up to 16G of 256k records
-	options.BenchOptions{1, 1 << 18, 64},
-	options.BenchOptions{1 << 16, 1 << 18, 64}, 9)
+var LargeBlockOpts = options.Log2("PrimeRecordCount", 1<<8, 1<<16).MustOptions( // up to 16G of 256k records
+	options.BenchOptions{RecordSize: 1 << 18, BatchSize: 64})
 
-var BlockSizeOpts = options.OptionsRange2pow( // up to 16G of scanning record sizes
-	options.BenchOptions{1 << 16, 1, 64},
-	options.BenchOptions{1 << 16, 1 << 18, 64}, 9)
+var blockSizes = options.Log2("RecordSize", 1<<10, 1<<18)
 
-var BatchSizeBlockSizeOpts = options.OptionsRange2pow(
-	options.BenchOptions{1 << 16, 1, 1},
-	options.BenchOptions{1 << 16, 1 << 18, 512}, 9)
+var BlockSizeOpts = blockSizes.MustOptions( // up to 16G of scanning record sizes
+	options.BenchOptions{PrimeRecordCount: 1 << 16, BatchSize: 64})
+
+var BatchSizeBlockSizeOpts = options.Product(blockSizes, options.Log2("BatchSize", 2, 512)).MustOptions(
+	options.BenchOptions{PrimeRecordCount: 1 << 16})
+
+var UpdateResizeOpts = options.Log2("PrimeRecordCount", 1<<8, 1<<16).MustOptions( // overwrite 256k records with 64k ones
+	options.BenchOptions{RecordSize: 1 << 18, BatchSize: 64, UpdateRecordSize: 1 << 16})
+
+var HitRatioOpts = options.Log2("HitRatio", 1.0/64, 1).MustOptions( // lookups hitting 1/64 to all keys
+	options.BenchOptions{PrimeRecordCount: 1 << 16, RecordSize: 1 << 12, BatchSize: 64})
+
+var ReopenOpts = options.Log2("PrimeRecordCount", 1<<12, 1<<20).MustOptions( // up to 4G of 4k records, 1024 writes lost in unclean mode
+	options.BenchOptions{RecordSize: 1 << 12, BatchSize: 1024})
+
+var VerifiedGetOpts = blockSizes.MustOptions( // BlockSizeOpts, checking values while reading
+	options.BenchOptions{PrimeRecordCount: 1 << 16, BatchSize: 64, Verify: options.VerifyOnRead})
+
+var CrashOpts = options.Values("BatchSize", 1, 16, 256).MustOptions( // crash after single writes and batches
+	options.BenchOptions{PrimeRecordCount: 1 << 10, RecordSize: 1 << 12})
+
+var TxnSizeOpts = options.Geometric("TxnSize", 1, 256, 4).MustOptions( // 1 to 256 keys per transaction
+	options.BenchOptions{PrimeRecordCount: 1 << 16, RecordSize: 1 << 12, BatchSize: 64})
+
+var TxnConcurrencyOpts = options.Log2("Concurrency", 1, 64).MustOptions( // skewed keys, so transactions conflict
+	options.BenchOptions{PrimeRecordCount: 1 << 16, RecordSize: 1 << 12, BatchSize: 64, KeyDistribution: options.KeyDistZipfian, TxnSize: 4})
+
+// DsParamOpts sweeps a datastore param over values, keeping other options
+func DsParamOpts(base options.BenchOptions, param string, values ...float64) []options.BenchOptions {
+	vals := make([]interface{}, len(values))
+	for i, v := range values {
+		vals[i] = v
+	}
+	base.DsParam = param
+	return options.Values("DsParamValue", vals...).MustOptions(base)
+}
+
+var FlatfsShardLengthOpts = DsParamOpts(
+	options.BenchOptions{PrimeRecordCount: 1 << 16, RecordSize: 1 << 12, BatchSize: 64},
+	"ShardLength", 1, 2, 3, 4)
+
+var FlatfsCidShardLengthOpts = DsParamOpts( // FlatfsShardLengthOpts with keys as stored by go-ipfs
+	options.BenchOptions{PrimeRecordCount: 1 << 16, RecordSize: 1 << 12, BatchSize: 64, KeyFormat: options.KeyFormatCid},
+	"ShardLength", 1, 2, 3, 4)
+
+var LeveldbBlockCacheOpts = DsParamOpts( // 1M to 256M cache over 256M of data
+	options.BenchOptions{PrimeRecordCount: 1 << 16, RecordSize: 1 << 12, BatchSize: 64, KeyDistribution: options.KeyDistZipfian},
+	"BlockCacheCapacity", 1<<20, 1<<22, 1<<24, 1<<26, 1<<28)
+
+var LeveldbWriteBufferOpts = DsParamOpts(
+	options.BenchOptions{PrimeRecordCount: 1 << 16, RecordSize: 1 << 12, BatchSize: 64},
+	"WriteBuffer", 1<<20, 1<<22, 1<<24, 1<<26)
+
+var LeveldbBloomOpts = DsParamOpts( // misses are what bloom filters help with
+	options.BenchOptions{PrimeRecordCount: 1 << 16, RecordSize: 1 << 12, BatchSize: 64, HitRatio: options.NoHits},
+	"BloomFilterBits", 0, 4, 8, 16)
 
 func BenchBasicGet() *Series {
 	return &Series{
@@ -23,7 +73,17 @@ func BenchBasicGet() *Series {
 		PlotName: "get",
 		Opts:     LargeBlockOpts,
 
-		Results: map[string]map[int]*parse.Benchmark{},
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchBasicGetSize() *Series {
+	return &Series{
+		Test:     "getsize",
+		PlotName: "getsize",
+		Opts:     LargeBlockOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
 	}
 }
 
@@ -33,7 +93,7 @@ func BenchBasicHas() *Series {
 		PlotName: "has",
 		Opts:     LargeBlockOpts,
 
-		Results: map[string]map[int]*parse.Benchmark{},
+		Results: map[string]map[string]*parse.Benchmark{},
 	}
 }
 
@@ -43,7 +103,7 @@ func BenchBasicAdd() *Series {
 		PlotName: "add",
 		Opts:     LargeBlockOpts,
 
-		Results: map[string]map[int]*parse.Benchmark{},
+		Results: map[string]map[string]*parse.Benchmark{},
 	}
 }
 
@@ -53,7 +113,27 @@ func BenchBasicAddBatch() *Series {
 		PlotName: "add-batch",
 		Opts:     LargeBlockOpts,
 
-		Results: map[string]map[int]*parse.Benchmark{},
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchBasicUpdate() *Series {
+	return &Series{
+		Test:     "update",
+		PlotName: "update",
+		Opts:     LargeBlockOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchBasicUpdateBatch() *Series {
+	return &Series{
+		Test:     "update-batch",
+		PlotName: "update-batch",
+		Opts:     LargeBlockOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
 	}
 }
 
@@ -65,7 +145,17 @@ func BenchBSizingGet() *Series {
 		PlotName: "get-bsize",
 		Opts:     BlockSizeOpts,
 
-		Results: map[string]map[int]*parse.Benchmark{},
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchBSizingGetSize() *Series {
+	return &Series{
+		Test:     "getsize",
+		PlotName: "getsize-bsize",
+		Opts:     BlockSizeOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
 	}
 }
 
@@ -75,7 +165,7 @@ func BenchBSizingHas() *Series {
 		PlotName: "has-bsize",
 		Opts:     BlockSizeOpts,
 
-		Results: map[string]map[int]*parse.Benchmark{},
+		Results: map[string]map[string]*parse.Benchmark{},
 	}
 }
 
@@ -85,7 +175,7 @@ func BenchBSizingAdd() *Series {
 		PlotName: "add-bsize",
 		Opts:     BlockSizeOpts,
 
-		Results: map[string]map[int]*parse.Benchmark{},
+		Results: map[string]map[string]*parse.Benchmark{},
 	}
 }
 
@@ -95,7 +185,81 @@ func BenchBSizingAddBatch() *Series {
 		PlotName: "add-batch-bsize",
 		Opts:     BlockSizeOpts,
 
-		Results: map[string]map[int]*parse.Benchmark{},
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchBSizingUpdate() *Series {
+	return &Series{
+		Test:     "update",
+		PlotName: "update-bsize",
+		Opts:     BlockSizeOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchBSizingUpdateBatch() *Series {
+	return &Series{
+		Test:     "update-batch",
+		PlotName: "update-batch-bsize",
+		Opts:     BlockSizeOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+// Hit ratio scanning
+
+func BenchHitRatioGet() *Series {
+	return &Series{
+		Test:     "get",
+		PlotName: "get-hitratio",
+		Opts:     HitRatioOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchHitRatioGetSize() *Series {
+	return &Series{
+		Test:     "getsize",
+		PlotName: "getsize-hitratio",
+		Opts:     HitRatioOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchHitRatioHas() *Series {
+	return &Series{
+		Test:     "has",
+		PlotName: "has-hitratio",
+		Opts:     HitRatioOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+// Overwriting with different size
+
+func BenchResizeUpdate() *Series {
+	return &Series{
+		Test:     "update",
+		PlotName: "update-resize",
+		Opts:     UpdateResizeOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchResizeUpdateBatch() *Series {
+	return &Series{
+		Test:     "update-batch",
+		PlotName: "update-batch-resize",
+		Opts:     UpdateResizeOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
 	}
 }
 
@@ -107,6 +271,146 @@ func BenchAddBatch() *Series {
 		PlotName: "add-batch-record-batch",
 		Opts:     BatchSizeBlockSizeOpts,
 
-		Results: map[string]map[int]*parse.Benchmark{},
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+// Datastore tuning
+
+func BenchFlatfsShardingGet() *Series {
+	return &Series{
+		Test:     "get",
+		PlotName: "get-flatfs-shard",
+		DsType:   "flatfs",
+		Opts:     FlatfsShardLengthOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchFlatfsShardingAdd() *Series {
+	return &Series{
+		Test:     "add",
+		PlotName: "add-flatfs-shard",
+		DsType:   "flatfs",
+		Opts:     FlatfsShardLengthOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchFlatfsCidShardingGet() *Series {
+	return &Series{
+		Test:     "get",
+		PlotName: "get-flatfs-shard-cid",
+		DsType:   "flatfs",
+		Opts:     FlatfsCidShardLengthOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchLeveldbBlockCacheGet() *Series {
+	return &Series{
+		Test:     "get",
+		PlotName: "get-leveldb-cache",
+		DsType:   "leveldb",
+		Opts:     LeveldbBlockCacheOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchLeveldbWriteBufferAddBatch() *Series {
+	return &Series{
+		Test:     "add-batch",
+		PlotName: "add-batch-leveldb-wbuf",
+		DsType:   "leveldb",
+		Opts:     LeveldbWriteBufferOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchLeveldbBloomHas() *Series {
+	return &Series{
+		Test:     "has",
+		PlotName: "has-leveldb-bloom",
+		DsType:   "leveldb",
+		Opts:     LeveldbBloomOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchTxnReadModifyWrite() *Series {
+	return &Series{
+		Test:     "txn-rmw",
+		PlotName: "txn-rmw",
+		Opts:     TxnSizeOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchTxnPut() *Series {
+	return &Series{
+		Test:     "txn-put",
+		PlotName: "txn-put",
+		Opts:     TxnSizeOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchTxnConflicts() *Series {
+	return &Series{
+		Test:     "txn-rmw",
+		PlotName: "txn-conflicts",
+		Opts:     TxnConcurrencyOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchReopen() *Series {
+	return &Series{
+		Test:     "reopen",
+		PlotName: "reopen",
+		Opts:     ReopenOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchReopenUnclean() *Series {
+	return &Series{
+		Test:     "reopen-unclean",
+		PlotName: "reopen-unclean",
+		Opts:     ReopenOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+// BenchCrash checks durability of acknowledged writes with each datastore's
+// SyncMode, lost and corrupted writes are kept in Series.Meta
+func BenchCrash() *Series {
+	return &Series{
+		Test:     "crash",
+		PlotName: "crash",
+		Opts:     CrashOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
+	}
+}
+
+func BenchVerifiedGet() *Series {
+	return &Series{
+		Test:     "get",
+		PlotName: "get-verified",
+		Opts:     VerifiedGetOpts,
+
+		Results: map[string]map[string]*parse.Benchmark{},
 	}
 }
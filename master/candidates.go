@@ -0,0 +1,107 @@
+package master
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ipfs/go-ds-bench/master/env"
+	"github.com/ipfs/go-ds-bench/options"
+)
+
+// candidates lists datastore and wrapper types the worker binary was built with
+func (w *Worker) candidates() (*options.WorkerInfo, error) {
+	init, ok := env.Handlers[w.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown remote type: %s", w.Type)
+	}
+
+	env, err := init(w.Spec)
+	if err != nil {
+		return nil, err
+	}
+	defer env.Close()
+
+	if err := env.CopyFile(workerBin, "worker.test", 0755); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	run := env.Cmd("./worker.test", []string{"-list-candidates"}, &out, os.Stderr)
+	if err := run(); err != nil {
+		return nil, err
+	}
+
+	var c options.WorkerInfo
+	if err := json.Unmarshal(out.Bytes(), &c); err != nil {
+		return nil, fmt.Errorf("parsing candidate list: %w", err)
+	}
+	return &c, nil
+}
+
+// validate checks that workers of every instance type can run all datastores
+// before any job is dispatched
+func (b *BatchSpec) validate() error {
+	for itype, workers := range b.Workers {
+		if len(workers) == 0 {
+			continue
+		}
+
+		avail, err := workers[0].candidates()
+		if err != nil {
+			return fmt.Errorf("listing datastores on %s: %w", itype, err)
+		}
+
+		have, names := infoIndex(avail.Datastores)
+		haveWrap, wrapNames := infoIndex(avail.Wrappers)
+
+		for _, ds := range b.Datastores {
+			c, ok := have[ds.Type]
+			if !ok {
+				return fmt.Errorf("datastore %s: type '%s' not available in worker binary on %s (have: %s)", ds.Name, ds.Type, itype, strings.Join(names, ", "))
+			}
+
+			if err := options.CheckParams(c.Params, ds.Params); err != nil {
+				return fmt.Errorf("datastore %s: %w", ds.Name, err)
+			}
+
+			for i, wrap := range ds.Wrappers {
+				c, ok := haveWrap[wrap.Type]
+				if !ok {
+					return fmt.Errorf("datastore %s: wrapper '%s' not available in worker binary on %s (have: %s)", ds.Name, wrap.Type, itype, strings.Join(wrapNames, ", "))
+				}
+
+				if err := options.CheckParams(c.Params, wrap.Params); err != nil {
+					return fmt.Errorf("datastore %s: wrapper %d (%s): %w", ds.Name, i, wrap.Type, err)
+				}
+			}
+
+			for _, series := range b.Jobs[itype] {
+				if !series.runsOn(ds) {
+					continue
+				}
+				for _, opt := range series.Opts {
+					if opt.DsParam == "" {
+						continue
+					}
+					if err := options.CheckParams(c.Params, opt.DatastoreParams(ds.Params)); err != nil {
+						return fmt.Errorf("series %s on datastore %s: %w", series.PlotName, ds.Name, err)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func infoIndex(infos []options.CandidateInfo) (map[string]options.CandidateInfo, []string) {
+	idx := map[string]options.CandidateInfo{}
+	names := make([]string, 0, len(infos))
+	for _, c := range infos {
+		idx[c.Name] = c
+		names = append(names, c.Name)
+	}
+	return idx, names
+}
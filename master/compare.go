@@ -0,0 +1,452 @@
+package master
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gonum/stat"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// metric is a value reported by benchmarks, compared by Compare
+type metric struct {
+	name         string
+	measured     int // parse.Benchmark.Measured bit
+	higherBetter bool
+	get          func(*parse.Benchmark) float64
+}
+
+var metrics = []metric{
+	{"ns/op", parse.NsPerOp, false, func(b *parse.Benchmark) float64 { return b.NsPerOp }},
+	{"MB/s", parse.MBPerS, true, func(b *parse.Benchmark) float64 { return b.MBPerS }},
+	{"B/op", parse.AllocedBytesPerOp, false, func(b *parse.Benchmark) float64 { return float64(b.AllocedBytesPerOp) }},
+	{"allocs/op", parse.AllocsPerOp, false, func(b *parse.Benchmark) float64 { return float64(b.AllocsPerOp) }},
+}
+
+// MetricNames are metrics Compare can check for regressions
+func MetricNames() []string {
+	out := make([]string, len(metrics))
+	for i, m := range metrics {
+		out[i] = m.name
+	}
+	return out
+}
+
+// CompareOptions configures Compare
+type CompareOptions struct {
+	Metrics   []string // metrics checked for regressions, see MetricNames
+	Threshold float64  // change in percent in the worse direction counted as a regression, 0 disables checks
+	Alpha     float64  // significance level, changes with p >= Alpha aren't regressions
+	PlotDir   string   // comparison plots are written here if set
+}
+
+// Regression is a point which got worse by more than CompareOptions.Threshold
+type Regression struct {
+	InstanceType string
+	Datastore    string
+	Name         string // test and options descriptor
+	Metric       string
+	Delta        float64 // percent
+	P            float64 // NaN with too few samples to reach Alpha, see minSamples
+	N            [2]int  // number of old and new samples
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s %s %s: %s %+.2f%% %s", r.InstanceType, r.Datastore, r.Name, r.Metric, r.Delta, testNote(r.P, r.N))
+}
+
+// compareKey identifies a point across result sets. Series running the same
+// test with the same options measure the same point, their results are
+// samples of it, as are results from more result sets on the same side.
+type compareKey struct {
+	InstanceType string
+	Datastore    string
+	Test         string
+	Descriptor   string
+}
+
+func (k compareKey) name() string {
+	return k.Test + "/" + k.Descriptor
+}
+
+// samples collects results of points, keys are in order of appearance
+func samples(batches []*BatchSpec) ([]compareKey, map[compareKey][]*parse.Benchmark, error) {
+	var keys []compareKey
+	out := map[compareKey][]*parse.Benchmark{}
+	for _, b := range batches {
+		pts, err := b.points()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, pt := range pts {
+			if pt.Result == nil {
+				continue
+			}
+			k := compareKey{pt.InstanceType, pt.Datastore, pt.Test, pt.Descriptor}
+			if _, ok := out[k]; !ok {
+				keys = append(keys, k)
+			}
+			out[k] = append(out[k], pt.Result)
+		}
+	}
+	return keys, out, nil
+}
+
+// Compare matches points of base and head result sets by instance type,
+// datastore, test and options, writes a table of changes of each metric to
+// w, and returns changes exceeding opts.Threshold.
+func Compare(w io.Writer, base, head []*BatchSpec, opts CompareOptions) ([]Regression, error) {
+	check := map[string]bool{}
+	for _, m := range opts.Metrics {
+		found := false
+		for _, known := range metrics {
+			found = found || known.name == m
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown metric '%s', expected one of %s", m, strings.Join(MetricNames(), ", "))
+		}
+		check[m] = true
+	}
+
+	keys, baseSamples, err := samples(base)
+	if err != nil {
+		return nil, err
+	}
+	headKeys, headSamples, err := samples(head)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []compareKey
+	for _, k := range keys {
+		if _, ok := headSamples[k]; ok {
+			matched = append(matched, k)
+		}
+	}
+	onlyHead := len(headKeys) - len(matched)
+	onlyBase := len(keys) - len(matched)
+
+	var regressions []Regression
+	untested := 0
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for start := 0; start < len(matched); {
+		// a table per instance type and datastore
+		end := start
+		for end < len(matched) && matched[end].InstanceType == matched[start].InstanceType && matched[end].Datastore == matched[start].Datastore {
+			end++
+		}
+		group := matched[start:end]
+		start = end
+
+		fmt.Fprintf(tw, "instance-type: %s\ndatastore: %s\n", group[0].InstanceType, group[0].Datastore)
+		for _, m := range metrics {
+			var rows []string
+			for _, k := range group {
+				bv, hv := metricValues(baseSamples[k], m), metricValues(headSamples[k], m)
+				if len(bv) == 0 || len(hv) == 0 {
+					continue
+				}
+
+				bmean, hmean := stat.Mean(bv, nil), stat.Mean(hv, nil)
+				delta := (hmean - bmean) / bmean * 100
+				// with too few samples the test can't reach alpha, such
+				// changes are only checked against the threshold
+				p := math.NaN()
+				if minP(len(bv), len(hv)) < opts.Alpha {
+					p = mannWhitneyU(bv, hv)
+				}
+
+				n := [2]int{len(bv), len(hv)}
+				deltaCol := fmt.Sprintf("%+.2f%%", delta)
+				switch {
+				case bmean == 0 && hmean == 0:
+					deltaCol = "~"
+				case bmean == 0:
+					deltaCol = "?"
+				case !math.IsNaN(p) && p >= opts.Alpha:
+					deltaCol = "~"
+				}
+				rows = append(rows, fmt.Sprintf("%s\t%s\t%s\t%s\t%s", k.name(), formatSamples(m.name, bv), formatSamples(m.name, hv), deltaCol, testNote(p, n)))
+
+				worse := delta
+				if m.higherBetter {
+					worse = -delta
+				}
+				significant := math.IsNaN(p) || p < opts.Alpha
+				if check[m.name] && opts.Threshold > 0 && bmean != 0 && worse > opts.Threshold && significant {
+					if math.IsNaN(p) {
+						untested++
+					}
+					regressions = append(regressions, Regression{
+						InstanceType: k.InstanceType,
+						Datastore:    k.Datastore,
+						Name:         k.name(),
+						Metric:       m.name,
+						Delta:        delta,
+						P:            p,
+						N:            n,
+					})
+				}
+			}
+			if len(rows) == 0 {
+				continue
+			}
+
+			fmt.Fprintf(tw, "\nname\told %s\tnew %s\tdelta\t\n", m.name, m.name)
+			for _, row := range rows {
+				fmt.Fprintln(tw, row)
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+	if err := tw.Flush(); err != nil {
+		return nil, err
+	}
+
+	if onlyBase > 0 || onlyHead > 0 {
+		fmt.Fprintf(w, "%d points only in old results, %d only in new\n", onlyBase, onlyHead)
+	}
+	if untested > 0 {
+		n := minSamples(opts.Alpha)
+		fmt.Fprintf(w, "warning: %d regressions are from points with too few samples to test, changes over the threshold are counted regardless of noise, p < %g needs at least %d+%d samples\n", untested, opts.Alpha, n, n)
+	}
+
+	if opts.PlotDir != "" {
+		if err := comparePlots(opts.PlotDir, base, head); err != nil {
+			return nil, err
+		}
+	}
+
+	return regressions, nil
+}
+
+func metricValues(benches []*parse.Benchmark, m metric) []float64 {
+	var out []float64
+	for _, b := range benches {
+		if b.Measured&m.measured != 0 {
+			out = append(out, m.get(b))
+		}
+	}
+	return out
+}
+
+// formatSamples formats the mean of samples, with the largest deviation from
+// it in percent when there are more
+func formatSamples(unit string, vs []float64) string {
+	mean := stat.Mean(vs, nil)
+	out := formatMetric(unit, mean)
+	if len(vs) < 2 || mean == 0 {
+		return out
+	}
+
+	var dev float64
+	for _, v := range vs {
+		dev = math.Max(dev, math.Abs(v-mean))
+	}
+	return fmt.Sprintf("%s ± %.0f%%", out, dev/mean*100)
+}
+
+func formatMetric(unit string, v float64) string {
+	if unit != "ns/op" {
+		return shortValue(v)
+	}
+	for _, u := range []string{"ns", "µs", "ms"} {
+		if v < 999.5 {
+			return fmt.Sprintf("%.3g%s", v, u)
+		}
+		v /= 1000
+	}
+	return fmt.Sprintf("%.3gs", v)
+}
+
+// testNote formats the p-value and sample counts like benchstat, points with
+// too few samples for a test are marked untested
+func testNote(p float64, n [2]int) string {
+	if math.IsNaN(p) {
+		return fmt.Sprintf("(n=%d+%d untested)", n[0], n[1])
+	}
+	return fmt.Sprintf("(p=%.3f n=%d+%d)", p, n[0], n[1])
+}
+
+// mannWhitneyU returns the two-sided p-value of the Mann-Whitney U test of
+// samples a and b coming from the same distribution. The exact distribution
+// of U is used without ties, a normal approximation with tie correction
+// otherwise.
+func mannWhitneyU(a, b []float64) float64 {
+	type sample struct {
+		v     float64
+		fromA bool
+	}
+	all := make([]sample, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, sample{v, true})
+	}
+	for _, v := range b {
+		all = append(all, sample{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	// rank sum of a, tied values get their average rank
+	var ra, ties float64
+	tied := false
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				ra += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			tied = true
+			ties += t*t*t - t
+		}
+		i = j
+	}
+
+	n1, n2 := len(a), len(b)
+	u := ra - float64(n1*(n1+1))/2
+
+	if !tied {
+		dist := uDist(n1, n2)
+		var total, below, above float64
+		for i, c := range dist {
+			total += c
+			if float64(i) <= u {
+				below += c
+			}
+			if float64(i) >= u {
+				above += c
+			}
+		}
+		return math.Min(1, 2*math.Min(below, above)/total)
+	}
+
+	n := float64(n1 + n2)
+	mu := float64(n1*n2) / 2
+	sigma := math.Sqrt(float64(n1*n2) / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := math.Max(0, math.Abs(u-mu)-0.5) / sigma
+	return math.Min(1, math.Erfc(z/math.Sqrt2))
+}
+
+// minP returns the smallest p-value mannWhitneyU returns for n1 and n2
+// samples, that of fully separated samples: 2 of C(n1+n2, n1) orderings are
+// as extreme. With 2+2 samples it is 1/3, a test can never be significant.
+func minP(n1, n2 int) float64 {
+	if n1 < 1 || n2 < 1 {
+		return 1
+	}
+	orderings := 1.0
+	for i := 1; i <= n1; i++ {
+		orderings = orderings * float64(n2+i) / float64(i)
+	}
+	return math.Min(1, 2/orderings)
+}
+
+// minSamples returns the number of samples on each side needed for a
+// significant change at alpha
+func minSamples(alpha float64) int {
+	n := 1
+	for minP(n, n) >= alpha && n < 1000 {
+		n++
+	}
+	return n
+}
+
+// uDist returns the number of orderings of n1 and n2 distinct samples giving
+// each value of U
+func uDist(n1, n2 int) []float64 {
+	// f[i][j][u] counts orderings of i and j samples with U = u, where
+	// f(i, j, u) = f(i-1, j, u-j) + f(i, j-1, u)
+	f := make([][][]float64, n1+1)
+	for i := range f {
+		f[i] = make([][]float64, n2+1)
+		for j := range f[i] {
+			f[i][j] = make([]float64, i*j+1)
+			if i == 0 || j == 0 {
+				f[i][j][0] = 1
+				continue
+			}
+			for u := range f[i][j] {
+				if u-j >= 0 && u-j < len(f[i-1][j]) {
+					f[i][j][u] += f[i-1][j][u-j]
+				}
+				if u < len(f[i][j-1]) {
+					f[i][j][u] += f[i][j-1][u]
+				}
+			}
+		}
+	}
+	return f[n1][n2]
+}
+
+// comparePlots plots results of both sides of each series of head, per
+// instance type, lines of base results are labeled "(old)", head ones
+// "(new)"
+func comparePlots(dir string, base, head []*BatchSpec) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	type seriesKey struct{ itype, plotName string }
+	results := map[seriesKey]map[string]map[string][]*parse.Benchmark{}
+	add := func(batches []*BatchSpec, label string) {
+		for _, b := range batches {
+			for itype, srs := range b.Jobs {
+				for _, s := range srs {
+					k := seriesKey{itype, s.PlotName}
+					if results[k] == nil {
+						results[k] = map[string]map[string][]*parse.Benchmark{}
+					}
+					for ds, res := range s.Results {
+						name := ds + " " + label
+						if results[k][name] == nil {
+							results[k][name] = map[string][]*parse.Benchmark{}
+						}
+						for desc, r := range res {
+							if r != nil {
+								results[k][name][desc] = append(results[k][name][desc], r)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	add(base, "(old)")
+	add(head, "(new)")
+
+	seen := map[seriesKey]bool{}
+	for _, b := range head {
+		for itype, srs := range b.Jobs {
+			if err := os.MkdirAll(dir+"/"+itype, 0755); err != nil {
+				return err
+			}
+			for _, s := range srs {
+				k := seriesKey{itype, s.PlotName}
+				if seen[k] || len(s.Opts) == 0 {
+					continue
+				}
+				seen[k] = true
+				if err := benchPlots(s.PlotName, dir+"/"+itype+"/", s.Opts, results[k], nil); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	plotWg.Wait()
+
+	return writeIndex(dir)
+}
@@ -0,0 +1,75 @@
+package master
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	for _, c := range []struct {
+		a, b []float64
+		p    float64
+	}{
+		// exact, separated samples: 2 of C(n1+n2, n1) orderings are as extreme
+		{[]float64{1, 2, 3}, []float64{4, 5, 6}, 2.0 / 20},
+		{[]float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 2.0 / 252},
+		{[]float64{10, 9, 8, 7, 6}, []float64{1, 2, 3, 4, 5}, 2.0 / 252},
+		{[]float64{1, 3, 5}, []float64{2, 4, 6}, 14.0 / 20},
+		// ties use the normal approximation
+		{[]float64{1, 1, 1}, []float64{1, 1, 1}, 1},
+		{[]float64{1, 1, 2, 2}, []float64{3, 3, 4, 4}, 0.0265},
+	} {
+		if p := mannWhitneyU(c.a, c.b); math.Abs(p-c.p) > 1e-3 {
+			t.Errorf("mannWhitneyU(%v, %v) = %f, expected %f", c.a, c.b, p, c.p)
+		}
+	}
+}
+
+func TestUDist(t *testing.T) {
+	dist := uDist(3, 4)
+	if len(dist) != 13 {
+		t.Fatalf("expected 13 values of U, got %d", len(dist))
+	}
+	var total float64
+	for i, c := range dist {
+		total += c
+		if c != dist[len(dist)-1-i] {
+			t.Errorf("distribution isn't symmetric: %v", dist)
+		}
+	}
+	if total != 35 {
+		t.Errorf("expected C(7, 3) = 35 orderings, got %v", total)
+	}
+}
+
+func TestMinP(t *testing.T) {
+	for _, c := range []struct {
+		n1, n2 int
+		p      float64
+	}{
+		{1, 1, 1},
+		{2, 2, 1.0 / 3},
+		{3, 3, 0.1},
+		{4, 4, 2.0 / 70},
+		{5, 5, 2.0 / 252},
+	} {
+		if p := minP(c.n1, c.n2); math.Abs(p-c.p) > 1e-9 {
+			t.Errorf("minP(%d, %d) = %f, expected %f", c.n1, c.n2, p, c.p)
+		}
+		a, b := make([]float64, c.n1), make([]float64, c.n2)
+		for i := range a {
+			a[i] = float64(i)
+		}
+		for i := range b {
+			b[i] = float64(c.n1 + i)
+		}
+		if c.n1 > 1 && c.n2 > 1 {
+			if p := mannWhitneyU(a, b); math.Abs(p-c.p) > 1e-9 {
+				t.Errorf("separated %d+%d samples: p = %f, expected minP %f", c.n1, c.n2, p, c.p)
+			}
+		}
+	}
+	if n := minSamples(0.05); n != 4 {
+		t.Errorf("expected 4+4 samples needed at alpha 0.05, got %d", n)
+	}
+}
@@ -0,0 +1,245 @@
+package master
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-ds-bench/options"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// ExportFormats are formats results can be exported in with Export
+var ExportFormats = []string{"csv", "json", "bench"}
+
+// point is a single result of a series, with everything identifying it
+type point struct {
+	InstanceType  string
+	Datastore     string
+	DatastoreType string
+	Tags          []string
+	Series        string // Series.PlotName
+	Test          string
+	Descriptor    string
+	Options       options.BenchOptions
+	Meta          map[string]string `json:",omitempty"`
+	Result        *parse.Benchmark  // nil for unsupported points
+}
+
+// descriptors returns descriptors of a datastore's results, in Opts order,
+// followed by results of points no longer in Opts
+func (s *Series) descriptors(ds string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, opt := range s.Opts {
+		desc := opt.Descriptor()
+		if _, ok := s.Results[ds][desc]; ok && !seen[desc] {
+			out = append(out, desc)
+		}
+		seen[desc] = true
+	}
+
+	var rest []string
+	for desc := range s.Results[ds] {
+		if !seen[desc] {
+			rest = append(rest, desc)
+		}
+	}
+	sort.Strings(rest)
+	return append(out, rest...)
+}
+
+// points flattens all results, ordered by instance type, series, datastore
+// and options
+func (b *BatchSpec) points() ([]point, error) {
+	itypes := make([]string, 0, len(b.Jobs))
+	for itype := range b.Jobs {
+		itypes = append(itypes, itype)
+	}
+	sort.Strings(itypes)
+
+	var out []point
+	for _, itype := range itypes {
+		for _, s := range b.Jobs[itype] {
+			dss := append([]options.WorkerDatastore{}, b.Datastores...)
+			var removed []string
+			for name := range s.Results {
+				if !hasDatastore(b.Datastores, name) {
+					removed = append(removed, name)
+				}
+			}
+			sort.Strings(removed)
+			for _, name := range removed {
+				dss = append(dss, options.WorkerDatastore{Name: name})
+			}
+
+			for _, ds := range dss {
+				for _, desc := range s.descriptors(ds.Name) {
+					opt, err := options.ParseDescriptor(desc)
+					if err != nil {
+						return nil, err
+					}
+					out = append(out, point{
+						InstanceType:  itype,
+						Datastore:     ds.Name,
+						DatastoreType: ds.Type,
+						Tags:          ds.Tags,
+						Series:        s.PlotName,
+						Test:          s.Test,
+						Descriptor:    desc,
+						Options:       opt,
+						Meta:          s.Meta[ds.Name][desc],
+						Result:        s.Results[ds.Name][desc],
+					})
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+func hasDatastore(dss []options.WorkerDatastore, name string) bool {
+	for _, ds := range dss {
+		if ds.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Export writes all results to w as csv, json or bench:
+//   - csv is a tidy table with a row per result: instance type, datastore,
+//     tags, series, every BenchOptions field, metrics and configuration
+//     reported by the worker, like sync-mode
+//   - json is an array of the same records
+//   - bench is Go benchmark text format benchstat understands, with instance
+//     type and datastore as configuration and options in benchmark names,
+//     like BenchmarkSpec/series=get/pre=256/size=262144/batch=64
+func (b *BatchSpec) Export(w io.Writer, format string) error {
+	pts, err := b.points()
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "csv":
+		return exportCSV(w, pts)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(pts)
+	case "bench":
+		return exportBench(w, pts)
+	default:
+		return fmt.Errorf("unknown export format '%s', expected one of %s", format, strings.Join(ExportFormats, ", "))
+	}
+}
+
+func exportCSV(w io.Writer, pts []point) error {
+	optType := reflect.TypeOf(options.BenchOptions{})
+
+	metaKeys := map[string]bool{}
+	for _, pt := range pts {
+		for k := range pt.Meta {
+			metaKeys[k] = true
+		}
+	}
+	var meta []string
+	for k := range metaKeys {
+		meta = append(meta, k)
+	}
+	sort.Strings(meta)
+
+	header := []string{"instance_type", "datastore", "datastore_type", "tags", "series", "test", "descriptor"}
+	for i := 0; i < optType.NumField(); i++ {
+		header = append(header, optType.Field(i).Name)
+	}
+	header = append(header, "iterations", "ns_per_op", "mb_per_s", "bytes_per_op", "allocs_per_op")
+	header = append(header, meta...)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, pt := range pts {
+		row := []string{pt.InstanceType, pt.Datastore, pt.DatastoreType, strings.Join(pt.Tags, ";"), pt.Series, pt.Test, pt.Descriptor}
+
+		opt := reflect.ValueOf(pt.Options)
+		for i := 0; i < opt.NumField(); i++ {
+			row = append(row, fmt.Sprint(opt.Field(i).Interface()))
+		}
+
+		metrics := make([]string, 5)
+		if r := pt.Result; r != nil {
+			metrics[0] = strconv.Itoa(r.N)
+			if r.Measured&parse.NsPerOp != 0 {
+				metrics[1] = strconv.FormatFloat(r.NsPerOp, 'g', -1, 64)
+			}
+			if r.Measured&parse.MBPerS != 0 {
+				metrics[2] = strconv.FormatFloat(r.MBPerS, 'g', -1, 64)
+			}
+			if r.Measured&parse.AllocedBytesPerOp != 0 {
+				metrics[3] = strconv.FormatUint(r.AllocedBytesPerOp, 10)
+			}
+			if r.Measured&parse.AllocsPerOp != 0 {
+				metrics[4] = strconv.FormatUint(r.AllocsPerOp, 10)
+			}
+		}
+		row = append(row, metrics...)
+
+		for _, k := range meta {
+			row = append(row, pt.Meta[k])
+		}
+
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// benchName names a point in benchmark text format, descriptor fields become
+// key=value parts of the name
+func benchName(pt point) string {
+	parts := []string{"BenchmarkSpec", "series=" + pt.Series}
+	for _, f := range strings.Split(pt.Descriptor, ",") {
+		parts = append(parts, strings.ReplaceAll(f, "/", "%2F"))
+	}
+	return strings.ReplaceAll(strings.Join(parts, "/"), " ", "_")
+}
+
+func exportBench(w io.Writer, pts []point) error {
+	var itype, ds string
+	for _, pt := range pts {
+		if pt.Result == nil {
+			continue
+		}
+
+		if pt.InstanceType != itype || pt.Datastore != ds {
+			sep := "\n"
+			if itype == "" {
+				sep = ""
+			}
+			itype, ds = pt.InstanceType, pt.Datastore
+			if _, err := fmt.Fprintf(w, "%sinstance-type: %s\ndatastore: %s\n", sep, itype, ds); err != nil {
+				return err
+			}
+		}
+
+		r := *pt.Result
+		r.Name = benchName(pt)
+		if _, err := fmt.Fprintln(w, r.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package master
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ipfs/go-ds-bench/options"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// exportBatch returns a batch with a supported point and an unsupported one,
+// which has no result
+func exportBatch() (*BatchSpec, []options.BenchOptions) {
+	opts := []options.BenchOptions{
+		{PrimeRecordCount: 256, RecordSize: 4096, BatchSize: 64, KeyPrefix: "/a b"},
+		{PrimeRecordCount: 512, RecordSize: 4096, BatchSize: 64, TxnSize: 8},
+	}
+	return &BatchSpec{
+		Datastores: []options.WorkerDatastore{{Type: "flatfs", Name: "flatfs-none", Tags: []string{"none"}}},
+		Jobs: map[string][]*Series{
+			"c5d": {{
+				Opts:     opts,
+				Test:     "get",
+				PlotName: "get",
+				Results: map[string]map[string]*parse.Benchmark{
+					"flatfs-none": {
+						opts[0].Descriptor(): {
+							N:                 1000,
+							NsPerOp:           1500,
+							AllocedBytesPerOp: 4096,
+							AllocsPerOp:       3,
+							Measured:          parse.NsPerOp | parse.AllocedBytesPerOp | parse.AllocsPerOp,
+						},
+						opts[1].Descriptor(): nil,
+					},
+				},
+				Meta: map[string]map[string]map[string]string{
+					"flatfs-none": {opts[0].Descriptor(): {"sync-mode": "per-op"}},
+				},
+			}},
+		},
+	}, opts
+}
+
+func TestExportCSV(t *testing.T) {
+	b, opts := exportBatch()
+	var buf bytes.Buffer
+	if err := b.Export(&buf, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %d", len(rows))
+	}
+
+	col := map[string]int{}
+	for i, name := range rows[0] {
+		col[name] = i
+	}
+	for i, opt := range opts {
+		row := rows[i+1]
+		if row[col["descriptor"]] != opt.Descriptor() {
+			t.Errorf("row %d: descriptor %s, expected %s", i, row[col["descriptor"]], opt.Descriptor())
+		}
+
+		// every option lands in its own column
+		v := reflect.ValueOf(opt)
+		for f := 0; f < v.NumField(); f++ {
+			name := v.Type().Field(f).Name
+			c, ok := col[name]
+			if !ok {
+				t.Fatalf("no column for option %s", name)
+			}
+			if expected := fmt.Sprint(v.Field(f).Interface()); row[c] != expected {
+				t.Errorf("row %d: %s = %q, expected %q", i, name, row[c], expected)
+			}
+		}
+	}
+
+	supported, unsupported := rows[1], rows[2]
+	for name, expected := range map[string]string{"instance_type": "c5d", "datastore_type": "flatfs", "tags": "none", "series": "get", "ns_per_op": "1500", "mb_per_s": "", "allocs_per_op": "3", "sync-mode": "per-op"} {
+		if supported[col[name]] != expected {
+			t.Errorf("supported %s = %q, expected %q", name, supported[col[name]], expected)
+		}
+	}
+	for _, name := range []string{"iterations", "ns_per_op", "bytes_per_op", "allocs_per_op", "sync-mode"} {
+		if unsupported[col[name]] != "" {
+			t.Errorf("unsupported point has %s = %q", name, unsupported[col[name]])
+		}
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	b, opts := exportBatch()
+	var buf bytes.Buffer
+	if err := b.Export(&buf, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var pts []point
+	if err := json.Unmarshal(buf.Bytes(), &pts); err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 2 {
+		t.Fatalf("expected 2 points, got %d", len(pts))
+	}
+	if pts[0].Options != opts[0] || pts[0].Result == nil || pts[0].Result.NsPerOp != 1500 {
+		t.Errorf("unexpected supported point %+v", pts[0])
+	}
+	if pts[1].Options != opts[1] || pts[1].Result != nil {
+		t.Errorf("unexpected unsupported point %+v", pts[1])
+	}
+}
+
+func TestExportBench(t *testing.T) {
+	b, _ := exportBatch()
+	var buf bytes.Buffer
+	if err := b.Export(&buf, "bench"); err != nil {
+		t.Fatal(err)
+	}
+	set, err := parse.ParseSet(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set) != 1 {
+		t.Fatalf("expected only the supported point, got %v", set)
+	}
+
+	name := "BenchmarkSpec/series=get/pre=256/size=4096/batch=64/prefix=%2Fa_b"
+	got := set[name]
+	if len(got) != 1 {
+		t.Fatalf("expected %s, got %v", name, set)
+	}
+	if got[0].N != 1000 || got[0].NsPerOp != 1500 || got[0].AllocedBytesPerOp != 4096 || got[0].AllocsPerOp != 3 {
+		t.Errorf("metrics didn't round trip: %+v", got[0])
+	}
+}
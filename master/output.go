@@ -0,0 +1,239 @@
+package master
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+// plotFormats are formats plots are written in, see SetPlotFormats
+var plotFormats = []string{"png"}
+
+// SetPlotFormats selects formats plots are written in: png, svg, pdf, or html
+// for self-contained interactive pages
+func SetPlotFormats(formats []string) error {
+	if len(formats) == 0 {
+		return fmt.Errorf("no plot formats")
+	}
+	for _, f := range formats {
+		switch f {
+		case "png", "svg", "pdf", "html":
+		default:
+			return fmt.Errorf("unknown plot format '%s', expected png, svg, pdf or html", f)
+		}
+	}
+	plotFormats = formats
+	return nil
+}
+
+// htmlPage is the data of an interactive html plot, drawn by
+// templates/plot.html
+type htmlPage struct {
+	Title    string
+	Charts   []htmlChart
+	Heatmaps []htmlHeatmap
+}
+
+// htmlChart is a line chart, with a line per datastore or per value of a
+// second swept option
+type htmlChart struct {
+	Title        string
+	Datastore    string `json:",omitempty"` // set on charts of a single datastore, which can be toggled
+	XName, YName string
+	LogX, LogY   bool
+	Lines        []htmlLine
+}
+
+type htmlLine struct {
+	Name   string
+	Points []htmlPoint
+}
+
+type htmlPoint struct {
+	X, Y float64
+	Err  float64 `json:",omitempty"` // standard deviation of Y
+}
+
+// newPoint returns a point, the deviation of a single sample is NaN, which
+// json can't encode, and is left out
+func newPoint(x, y, stddev float64) htmlPoint {
+	if math.IsNaN(stddev) {
+		stddev = 0
+	}
+	return htmlPoint{X: x, Y: y, Err: stddev}
+}
+
+// htmlHeatmap is a datastore's metric over a 2-D sweep
+type htmlHeatmap struct {
+	Title        string
+	Datastore    string
+	XName, YName string
+	Xs, Ys       []float64
+	Z            [][]*float64 // [column][row], nil without results
+}
+
+// writePlot saves plots to dir/base.<format> for each selected format, tiled
+// if there are more of them, each w by h. page describes the same plots for
+// html output.
+func writePlot(dir, base string, plots []*plot.Plot, w, h vg.Length, page *htmlPage) error {
+	if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+		return err
+	}
+	base = filepath.Join(dir, strings.Replace(base, "/", "", -1))
+
+	for _, format := range plotFormats {
+		var err error
+		switch {
+		case format == "html":
+			err = writeHTML(base+".html", page)
+		case len(plots) == 1:
+			err = plots[0].Save(w, h, base+"."+format)
+		default:
+			err = saveTiled(plots, base+"."+format, format, w, h)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeHTML(fName string, page *htmlPage) error {
+	f, err := os.Create(fName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return templates.ExecuteTemplate(f, "plot.html", page)
+}
+
+// saveTiled draws plots in a grid of tiles, each w by h, to fName
+func saveTiled(plots []*plot.Plot, fName string, format string, w, h vg.Length) error {
+	cols := int(math.Ceil(math.Sqrt(float64(len(plots)))))
+	rows := (len(plots) + cols - 1) / cols
+
+	tiled := make([][]*plot.Plot, rows)
+	for j := range tiled {
+		tiled[j] = make([]*plot.Plot, cols)
+		copy(tiled[j], plots[j*cols:])
+	}
+
+	c, err := draw.NewFormattedCanvas(vg.Length(cols)*w, vg.Length(rows)*h, format)
+	if err != nil {
+		return err
+	}
+	tiles := draw.Tiles{
+		Rows: rows, Cols: cols,
+		PadX: vg.Millimeter * 4, PadY: vg.Millimeter * 4,
+		PadTop: vg.Millimeter * 2, PadBottom: vg.Millimeter * 2,
+		PadLeft: vg.Millimeter * 2, PadRight: vg.Millimeter * 2,
+	}
+	canvases := plot.Align(tiled, tiles, draw.New(c))
+	for j, row := range tiled {
+		for i, p := range row {
+			if p != nil {
+				p.Draw(canvases[j][i])
+			}
+		}
+	}
+
+	f, err := os.Create(fName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = c.WriteTo(f)
+	return err
+}
+
+// indexDir is a directory of plots listed in the index page
+type indexDir struct {
+	Path  string
+	ID    string // anchor of the directory's section
+	Plots []indexPlot
+}
+
+type indexPlot struct {
+	Name    string
+	Path    string
+	Formats []string // file extensions, sorted
+	Image   string   // png or svg shown as a preview, if written
+}
+
+// writeIndex writes root/index.html, linking every plot under root
+func writeIndex(root string) error {
+	byDir := map[string]map[string]*indexPlot{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		ext := strings.TrimPrefix(filepath.Ext(path), ".")
+		switch ext {
+		case "png", "svg", "pdf", "html":
+		default:
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "index.html" {
+			return nil
+		}
+		dir, name := filepath.Split(strings.TrimSuffix(rel, "."+ext))
+		if byDir[dir] == nil {
+			byDir[dir] = map[string]*indexPlot{}
+		}
+		p := byDir[dir][name]
+		if p == nil {
+			p = &indexPlot{Name: name, Path: filepath.ToSlash(dir + name)}
+			byDir[dir][name] = p
+		}
+		p.Formats = append(p.Formats, ext)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var dirs []indexDir
+	for dir, plots := range byDir {
+		d := indexDir{Path: filepath.ToSlash(dir)}
+		d.ID = "d-" + strings.NewReplacer("/", "_", " ", "_").Replace(d.Path)
+		for _, p := range plots {
+			sort.Strings(p.Formats)
+			for _, f := range p.Formats {
+				if f == "png" || f == "svg" {
+					p.Image = p.Path + "." + f
+					break
+				}
+			}
+			d.Plots = append(d.Plots, *p)
+		}
+		sort.Slice(d.Plots, func(i, j int) bool { return d.Plots[i].Name < d.Plots[j].Name })
+		dirs = append(dirs, d)
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Path < dirs[j].Path })
+
+	f, err := os.Create(filepath.Join(root, "index.html"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return templates.ExecuteTemplate(f, "index.html", dirs)
+}
@@ -2,9 +2,7 @@ package master
 
 import (
 	"fmt"
-	"os"
 	"sort"
-	"strings"
 	"sync"
 
 	"github.com/gonum/stat"
@@ -31,7 +29,7 @@ func (p *pt) Swap(i, j int) {
 	p.YErrors[i], p.YErrors[j] = p.YErrors[j], p.YErrors[i]
 }
 
-func genplots(plotName string, pathPrefix string, bopts []options.BenchOptions, results map[string]map[int][]*parse.Benchmark, x *xsel, y *ysel, yscale plot.Normalizer, ymarker plot.Ticker, suffix string) error {
+func genplots(plotName string, pathPrefix string, bopts []options.BenchOptions, results map[string]map[string][]*parse.Benchmark, x *xsel, y *ysel, yscale plot.Normalizer, ymarker plot.Ticker, suffix string) error {
 	plotWg.Add(1)
 	go func() {
 		defer plotWg.Done()
@@ -48,18 +46,27 @@ func genplots(plotName string, pathPrefix string, bopts []options.BenchOptions,
 
 		p.Add(plotter.NewGrid())
 
+		_, logY := yscale.(ZeroLogScale)
+		chart := htmlChart{Title: plotName, XName: x.name, YName: y.name, LogX: true, LogY: logY}
+
 		var lp []interface{}
 		var lpe []interface{}
-		for dsname, p := range results {
+		// datastores not supporting the benchmark have no results and are left out
+		for _, dsname := range sortedNames(results) {
+			p := results[dsname]
 			byX := map[float64][]float64{}
 
 			var pts pt
 			//pts := make(plotter.XYs, 0, len(p))
 
-			for n, benches := range p {
+			for desc, benches := range p {
+				opt, err := options.ParseDescriptor(desc)
+				if err != nil {
+					panic(err)
+				}
 				for _, bench := range benches {
 					if bench != nil {
-						byX[x.sel(bopts[n])] = append(byX[x.sel(bopts[n])], y.sel(bench))
+						byX[x.sel(opt)] = append(byX[x.sel(opt)], y.sel(bench))
 					}
 				}
 			}
@@ -78,6 +85,12 @@ func genplots(plotName string, pathPrefix string, bopts []options.BenchOptions,
 
 			lp = append(lp, dsname, &pts)
 			lpe = append(lpe, &pts)
+
+			line := htmlLine{Name: dsname}
+			for i, xy := range pts.XYs {
+				line.Points = append(line.Points, newPoint(xy.X, xy.Y, pts.YErrors[i].High*2))
+			}
+			chart.Lines = append(chart.Lines, line)
 		}
 
 		if err := plotutil.AddLinePoints(p, lp...); err != nil {
@@ -88,12 +101,9 @@ func genplots(plotName string, pathPrefix string, bopts []options.BenchOptions,
 			//panic(err)
 		}
 
-		if err := os.Mkdir(pathPrefix+plotName, 0755); err != nil && !os.IsExist(err) {
-			panic(err)
-		}
-		fName := fmt.Sprintf("%s-%s%s.png", x.name, y.name, suffix)
-		fName = strings.Replace(fName, "/", "", -1)
-		if err := p.Save(8*vg.Inch, 6*vg.Inch, pathPrefix+plotName+"/"+fName); err != nil {
+		fName := fmt.Sprintf("%s-%s%s", x.name, y.name, suffix)
+		page := &htmlPage{Title: plotName + " " + fName, Charts: []htmlChart{chart}}
+		if err := writePlot(pathPrefix+plotName, fName, []*plot.Plot{p}, 8*vg.Inch, 6*vg.Inch, page); err != nil {
 			panic(err)
 		}
 	}()
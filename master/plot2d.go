@@ -0,0 +1,307 @@
+package master
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/gonum/stat"
+	"github.com/ipfs/go-ds-bench/options"
+
+	"golang.org/x/tools/benchmark/parse"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/text"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// sweeps2d returns pairs of swept options forming a 2-D sweep, where neither
+// option determines the other, like record and batch size in
+// BatchSizeBlockSizeOpts. Options changing together, as in a Zip, aren't
+// paired.
+func sweeps2d(bopts []options.BenchOptions, sels map[int]*xsel) [][2]*xsel {
+	keys := make([]int, 0, len(sels))
+	for k := range sels {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	var out [][2]*xsel
+	for i, ka := range keys {
+		for _, kb := range keys[i+1:] {
+			a, b := sels[ka], sels[kb]
+
+			as, bs, pairs := map[float64]bool{}, map[float64]bool{}, map[[2]float64]bool{}
+			for _, opt := range bopts {
+				as[a.sel(opt)] = true
+				bs[b.sel(opt)] = true
+				pairs[[2]float64{a.sel(opt), b.sel(opt)}] = true
+			}
+			if len(pairs) > len(as) && len(pairs) > len(bs) {
+				out = append(out, [2]*xsel{a, b})
+			}
+		}
+	}
+	return out
+}
+
+// axisValues returns sorted distinct values of x over bopts
+func axisValues(bopts []options.BenchOptions, x *xsel) []float64 {
+	seen := map[float64]bool{}
+	var out []float64
+	for _, opt := range bopts {
+		if v := x.sel(opt); !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	sort.Float64s(out)
+	return out
+}
+
+// pointValues groups y values of a datastore's results by values of a and b
+func pointValues(results map[string][]*parse.Benchmark, a, b *xsel, y *ysel) map[[2]float64][]float64 {
+	out := map[[2]float64][]float64{}
+	for desc, benches := range results {
+		opt, err := options.ParseDescriptor(desc)
+		if err != nil {
+			panic(err)
+		}
+		pt := [2]float64{a.sel(opt), b.sel(opt)}
+		for _, bench := range benches {
+			if bench != nil {
+				out[pt] = append(out[pt], y.sel(bench))
+			}
+		}
+	}
+	return out
+}
+
+// sortedNames returns datastores with results, in order
+func sortedNames(results map[string]map[string][]*parse.Benchmark) []string {
+	var out []string
+	for name, res := range results {
+		if hasResults(res) {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func hasResults(res map[string][]*parse.Benchmark) bool {
+	for _, benches := range res {
+		for _, bench := range benches {
+			if bench != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// grid2d is a datastore's mean metric over a 2-D sweep, missing points are
+// NaN. Columns and rows are placed at their index, so each value gets a
+// same sized cell no matter how values are spaced.
+type grid2d struct {
+	xs, ys []float64
+	z      [][]float64 // [column][row]
+}
+
+func (g *grid2d) Dims() (c, r int)   { return len(g.xs), len(g.ys) }
+func (g *grid2d) Z(c, r int) float64 { return g.z[c][r] }
+func (g *grid2d) X(c int) float64    { return float64(c) }
+func (g *grid2d) Y(r int) float64    { return float64(r) }
+
+// colored returns the number of cells with a color
+func (g *grid2d) colored() int {
+	var n int
+	for _, col := range g.z {
+		for _, v := range col {
+			if !math.IsNaN(v) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// indexTicks labels index placed grid cells with their values
+func indexTicks(values []float64) plot.ConstantTicks {
+	ticks := make(plot.ConstantTicks, len(values))
+	for i, v := range values {
+		ticks[i] = plot.Tick{Value: float64(i), Label: strconv.FormatFloat(v, 'g', -1, 64)}
+	}
+	return ticks
+}
+
+// shortValue formats v with 3 significant digits and a k, M or G suffix, to
+// fit in heatmap cells
+func shortValue(v float64) string {
+	for _, unit := range []string{"", "k", "M"} {
+		if math.Abs(v) < 999.5 {
+			return strconv.FormatFloat(v, 'g', 3, 64) + unit
+		}
+		v /= 1000
+	}
+	return strconv.FormatFloat(v, 'g', 3, 64) + "G"
+}
+
+// genheatmaps draws a heatmap of y over a 2-D sweep of x and l for each
+// datastore, side by side. Cells are labeled with the mean and colored by its
+// log2, contour lines are added when all cells are colored.
+func genheatmaps(plotName string, pathPrefix string, bopts []options.BenchOptions, results map[string]map[string][]*parse.Benchmark, x, l *xsel, y *ysel) error {
+	plotWg.Add(1)
+	go func() {
+		defer plotWg.Done()
+
+		xs, ls := axisValues(bopts, x), axisValues(bopts, l)
+
+		var plots []*plot.Plot
+		page := &htmlPage{}
+		for _, dsname := range sortedNames(results) {
+			vals := pointValues(results[dsname], x, l, y)
+
+			g := &grid2d{xs: xs, ys: ls, z: make([][]float64, len(xs))}
+			hm := htmlHeatmap{Title: dsname + " " + y.name, Datastore: dsname, XName: x.name, YName: l.name, Xs: xs, Ys: ls, Z: make([][]*float64, len(xs))}
+			var labels plotter.XYLabels
+			for c, xv := range xs {
+				g.z[c] = make([]float64, len(ls))
+				hm.Z[c] = make([]*float64, len(ls))
+				for r, lv := range ls {
+					ys := vals[[2]float64{xv, lv}]
+					if len(ys) == 0 {
+						g.z[c][r] = math.NaN()
+						continue
+					}
+					mean := stat.Mean(ys, nil)
+					hm.Z[c][r] = &mean
+					g.z[c][r] = math.NaN() // zero metrics, like MB/s of benchmarks not setting bytes, have no color
+					if mean > 0 {
+						g.z[c][r] = math.Log2(mean)
+					}
+					labels.XYs = append(labels.XYs, plotter.XY{X: float64(c), Y: float64(r)})
+					labels.Labels = append(labels.Labels, shortValue(mean))
+				}
+			}
+			if len(labels.Labels) == 0 {
+				continue
+			}
+
+			p := plot.New()
+			p.Title.Text = fmt.Sprintf("%s %s", dsname, y.name)
+			p.X.Label.Text = x.name
+			p.Y.Label.Text = l.name
+			p.X.Tick.Marker = indexTicks(xs)
+			p.Y.Tick.Marker = indexTicks(ls)
+			p.X.Padding, p.Y.Padding = 0, 0
+
+			switch g.colored() {
+			case len(xs) * len(ls):
+				ct := plotter.NewContour(g, nil, nil)
+				ct.LineStyles = []draw.LineStyle{{Color: color.Gray{Y: 96}, Width: vg.Points(0.5), Dashes: []vg.Length{vg.Points(2), vg.Points(2)}}}
+				p.Add(plotter.NewHeatMap(g, palette.Heat(64, 1)), ct)
+			case 0:
+			default:
+				p.Add(plotter.NewHeatMap(g, palette.Heat(64, 1)))
+			}
+
+			lp, err := plotter.NewLabels(labels)
+			if err != nil {
+				panic(err)
+			}
+			for i := range lp.TextStyle {
+				lp.TextStyle[i].Font.Size = vg.Points(7)
+				lp.TextStyle[i].XAlign = text.XCenter
+				lp.TextStyle[i].YAlign = text.YCenter
+			}
+			p.Add(lp)
+
+			plots = append(plots, p)
+			page.Heatmaps = append(page.Heatmaps, hm)
+		}
+		if len(plots) == 0 {
+			return
+		}
+
+		fName := fmt.Sprintf("heatmap-%s-%s-%s", x.name, l.name, y.name)
+		page.Title = plotName + " " + fName
+		if err := writePlot(pathPrefix+plotName, fName, plots, 5*vg.Inch, 4*vg.Inch, page); err != nil {
+			panic(err)
+		}
+	}()
+	return nil
+}
+
+// gensmallmultiples draws y over x for each datastore side by side, with a
+// line per value of l, so points of a 2-D sweep aren't averaged together
+func gensmallmultiples(plotName string, pathPrefix string, bopts []options.BenchOptions, results map[string]map[string][]*parse.Benchmark, x, l *xsel, y *ysel, yscale plot.Normalizer, ymarker plot.Ticker, suffix string) error {
+	plotWg.Add(1)
+	go func() {
+		defer plotWg.Done()
+
+		xs, ls := axisValues(bopts, x), axisValues(bopts, l)
+
+		_, logY := yscale.(ZeroLogScale)
+
+		var plots []*plot.Plot
+		page := &htmlPage{}
+		for _, dsname := range sortedNames(results) {
+			vals := pointValues(results[dsname], x, l, y)
+			chart := htmlChart{Title: dsname, Datastore: dsname, XName: x.name, YName: y.name, LogX: true, LogY: logY}
+
+			p := plot.New()
+			p.Title.Text = dsname
+			p.Y.Label.Text = y.name
+			p.X.Label.Text = x.name
+			p.X.Scale = ZeroLogScale{}
+			p.Y.Scale = yscale
+			p.Legend.Top = true
+			p.X.Tick.Marker = Log2Ticks{}
+			p.Y.Tick.Marker = ymarker
+			p.Add(plotter.NewGrid())
+
+			var lp []interface{}
+			for _, lv := range ls {
+				name := fmt.Sprintf("%s=%g", l.name, lv)
+				line := htmlLine{Name: name}
+				var pts plotter.XYs
+				for _, xv := range xs {
+					if ys := vals[[2]float64{xv, lv}]; len(ys) > 0 {
+						y, stddev := stat.MeanStdDev(ys, nil)
+						pts = append(pts, plotter.XY{X: xv, Y: y})
+						line.Points = append(line.Points, newPoint(xv, y, stddev))
+					}
+				}
+				if len(pts) > 0 {
+					lp = append(lp, name, pts)
+					chart.Lines = append(chart.Lines, line)
+				}
+			}
+			if len(lp) == 0 {
+				continue
+			}
+			if err := plotutil.AddLinePoints(p, lp...); err != nil {
+				panic(err)
+			}
+
+			plots = append(plots, p)
+			page.Charts = append(page.Charts, chart)
+		}
+		if len(plots) == 0 {
+			return
+		}
+
+		fName := fmt.Sprintf("%s-%s-by-%s%s", x.name, y.name, l.name, suffix)
+		page.Title = plotName + " " + fName
+		if err := writePlot(pathPrefix+plotName, fName, plots, 5*vg.Inch, 4*vg.Inch, page); err != nil {
+			panic(err)
+		}
+	}()
+	return nil
+}
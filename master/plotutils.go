@@ -70,6 +70,37 @@ var xselBatchSize = &xsel{
 	},
 }
 
+var xselHitRatio = &xsel{
+	name: "hit-ratio",
+	sel: func(opt options.BenchOptions) float64 {
+		return opt.HitRatio
+	},
+}
+
+var xselTxnSize = &xsel{
+	name: "txn-size",
+	sel: func(opt options.BenchOptions) float64 {
+		return float64(opt.TxnSize)
+	},
+}
+
+var xselConcurrency = &xsel{
+	name: "concurrency",
+	sel: func(opt options.BenchOptions) float64 {
+		return float64(opt.Concurrency)
+	},
+}
+
+// xselDsParam selects the swept datastore param
+func xselDsParam(param string) *xsel {
+	return &xsel{
+		name: param,
+		sel: func(opt options.BenchOptions) float64 {
+			return opt.DsParamValue
+		},
+	}
+}
+
 type Log2Ticks struct{}
 
 var _ plot.Ticker = Log2Ticks{}
@@ -3,8 +3,11 @@ package master
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"gonum.org/v1/plot"
 	"io/ioutil"
+	"reflect"
+	"strconv"
 	"sync"
 
 	"github.com/ipfs/go-ds-bench/options"
@@ -19,22 +22,45 @@ type Series struct {
 	Opts     []options.BenchOptions
 	Test     string // defined in Worker/worker_test.go
 	PlotName string
+	DsType   string // only run against datastores of this type, all if empty
 
-	// ds -> Opts
-	Results map[string]map[int]*parse.Benchmark
+	// ds -> Opts descriptor, see options.BenchOptions.Descriptor
+	Results map[string]map[string]*parse.Benchmark
+	// ds -> Opts descriptor -> benchfmt configuration of the run, like sync-mode
+	Meta map[string]map[string]map[string]string `json:",omitempty"`
 
 	lk sync.Mutex
 }
 
+// runsOn tells if the series should be run against the datastore
+func (s *Series) runsOn(ds options.WorkerDatastore) bool {
+	return s.DsType == "" || s.DsType == ds.Type
+}
+
+// setMeta records configuration of a finished run, s.lk must be held
+func (s *Series) setMeta(ds string, desc string, meta map[string]string) {
+	if len(meta) == 0 {
+		return
+	}
+	if s.Meta == nil {
+		s.Meta = map[string]map[string]map[string]string{}
+	}
+	if s.Meta[ds] == nil {
+		s.Meta[ds] = map[string]map[string]string{}
+	}
+	s.Meta[ds][desc] = meta
+}
+
+// todo returns indexes of Opts without results for the datastore
 func (s *Series) todo(ds string) []int {
 	out := make([]int, 0, len(s.Opts))
 
 	if s.Results[ds] == nil {
-		s.Results[ds] = map[int]*parse.Benchmark{}
+		s.Results[ds] = map[string]*parse.Benchmark{}
 	}
 
-	for n := range s.Opts {
-		if _, ok := s.Results[ds][n]; !ok {
+	for n, opt := range s.Opts {
+		if _, ok := s.Results[ds][opt.Descriptor()]; !ok {
 			out = append(out, n)
 		}
 	}
@@ -42,6 +68,50 @@ func (s *Series) todo(ds string) []int {
 	return out
 }
 
+// UnmarshalJSON loads a series, migrating results keyed by index into Opts,
+// as stored by older versions, to descriptors
+func (s *Series) UnmarshalJSON(b []byte) error {
+	type plain Series
+	if err := json.Unmarshal(b, (*plain)(s)); err != nil {
+		return err
+	}
+
+	for ds, results := range s.Results {
+		if err := s.migrateKeys(ds, results); err != nil {
+			return err
+		}
+	}
+	for ds, meta := range s.Meta {
+		if err := s.migrateKeys(ds, meta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateKeys rekeys index keyed entries of a Results or Meta map by
+// descriptors, and checks other keys are valid descriptors
+func (s *Series) migrateKeys(ds string, m interface{}) error {
+	v := reflect.ValueOf(m)
+	for _, k := range v.MapKeys() {
+		key := k.String()
+		n, err := strconv.Atoi(key)
+		if err != nil {
+			if _, err := options.ParseDescriptor(key); err != nil {
+				return fmt.Errorf("series %s, datastore %s: %w", s.PlotName, ds, err)
+			}
+			continue
+		}
+		if n < 0 || n >= len(s.Opts) {
+			return fmt.Errorf("series %s, datastore %s: result index %d out of %d options", s.PlotName, ds, n, len(s.Opts))
+		}
+
+		v.SetMapIndex(reflect.ValueOf(s.Opts[n].Descriptor()), v.MapIndex(k))
+		v.SetMapIndex(k, reflect.Value{})
+	}
+	return nil
+}
+
 /*
 func (s *Series) benchSeries(f ...DsFilter) error {
 	log.Printf("BEGIN %s", s.PlotName)
@@ -103,16 +173,16 @@ func (s *Series) saveResults() error {
 }
 
 // doAvg averages items across category
-func (s *Series) doAvg(in map[string]map[string]map[int]*parse.Benchmark) map[string]map[int][]*parse.Benchmark {
-	out := map[string]map[int][]*parse.Benchmark{}
+func (s *Series) doAvg(in map[string]map[string]map[string]*parse.Benchmark) map[string]map[string][]*parse.Benchmark {
+	out := map[string]map[string][]*parse.Benchmark{}
 
 	for cat, items := range in {
-		avg := make(map[int][]*parse.Benchmark, len(s.Opts))
+		avg := make(map[string][]*parse.Benchmark, len(s.Opts))
 
 		for _, e := range items {
-			for i, bench := range e {
+			for desc, bench := range e {
 				if bench != nil {
-					avg[i] = append(avg[i], bench)
+					avg[desc] = append(avg[desc], bench)
 				}
 			}
 		}
@@ -122,7 +192,25 @@ func (s *Series) doAvg(in map[string]map[string]map[int]*parse.Benchmark) map[st
 	return out
 }
 
-func benchPlots(plotName string, path string, bopts []options.BenchOptions, results map[string]map[int][]*parse.Benchmark) error {
+// yplots are metrics plotted against swept options, each on a linear and a
+// log scale
+var yplots = []struct {
+	y      *ysel
+	scale  plot.Normalizer
+	marker plot.Ticker
+	suffix string
+}{
+	{yselNsPerOp, plot.LinearScale{}, TimeTicks{plot.DefaultTicks{}}, ""},
+	{yselNsPerOp, ZeroLogScale{}, TimeTicks{Log2Ticks{}}, "-log"},
+	{yselAllocs, plot.LinearScale{}, plot.DefaultTicks{}, ""},
+	{yselAllocs, ZeroLogScale{}, Log2Ticks{}, "-log"},
+	{yselAlocKB, plot.LinearScale{}, plot.DefaultTicks{}, ""},
+	{yselAlocKB, ZeroLogScale{}, Log2Ticks{}, "-log"},
+	{yselMBps, plot.LinearScale{}, plot.DefaultTicks{}, ""},
+	{yselMBps, ZeroLogScale{}, Log2Ticks{}, "-log"},
+}
+
+func benchPlots(plotName string, path string, bopts []options.BenchOptions, results map[string]map[string][]*parse.Benchmark, base *baseline) error {
 	sels := map[int]*xsel{}
 
 	for _, bopt := range bopts[1:] {
@@ -135,39 +223,74 @@ func benchPlots(plotName string, path string, bopts []options.BenchOptions, resu
 		if bopts[0].PrimeRecordCount != bopt.PrimeRecordCount {
 			sels[2] = xselPrimeRecs
 		}
+		if bopts[0].HitRatio != bopt.HitRatio {
+			sels[3] = xselHitRatio
+		}
+		if bopts[0].TxnSize != bopt.TxnSize {
+			sels[5] = xselTxnSize
+		}
+		if bopts[0].Concurrency != bopt.Concurrency {
+			sels[6] = xselConcurrency
+		}
+		if bopts[0].DsParamValue != bopt.DsParamValue {
+			sels[4] = xselDsParam(bopts[0].DsParam)
+		}
 	}
 
-	for _, ixsel := range sels {
-		if err := genplots(plotName, path, bopts, results, ixsel, yselNsPerOp, plot.LinearScale{}, TimeTicks{plot.DefaultTicks{}}, ""); err != nil {
-			return err
-		}
+	// lines over an option swept along with another would average points
+	// of the other one, such pairs get heatmaps and a line per value instead
+	pairs := sweeps2d(bopts, sels)
+	in2d := map[*xsel]bool{}
+	for _, pair := range pairs {
+		in2d[pair[0]], in2d[pair[1]] = true, true
+	}
 
-		if err := genplots(plotName, path, bopts, results, ixsel, yselNsPerOp, ZeroLogScale{}, TimeTicks{Log2Ticks{}}, "-log"); err != nil {
-			return err
-		}
+	// speedup over a baseline, drawn like any other metric
+	var normalized map[string]map[string][]*parse.Benchmark
+	var speedup *ysel
+	if base != nil {
+		normalized, speedup = base.normalize(results)
+	}
 
-		if err := genplots(plotName, path, bopts, results, ixsel, yselAllocs, plot.LinearScale{}, plot.DefaultTicks{}, ""); err != nil {
-			return err
+	for _, ixsel := range sels {
+		if in2d[ixsel] {
+			continue
 		}
-
-		if err := genplots(plotName, path, bopts, results, ixsel, yselAllocs, ZeroLogScale{}, Log2Ticks{}, "-log"); err != nil {
-			return err
+		for _, yp := range yplots {
+			if err := genplots(plotName, path, bopts, results, ixsel, yp.y, yp.scale, yp.marker, yp.suffix); err != nil {
+				return err
+			}
 		}
-
-		if err := genplots(plotName, path, bopts, results, ixsel, yselAlocKB, plot.LinearScale{}, plot.DefaultTicks{}, ""); err != nil {
-			return err
+		if len(normalized) > 0 {
+			if err := genplots(plotName, path, bopts, normalized, ixsel, speedup, ZeroLogScale{}, RatioTicks{}, ""); err != nil {
+				return err
+			}
 		}
+	}
 
-		if err := genplots(plotName, path, bopts, results, ixsel, yselAlocKB, ZeroLogScale{}, Log2Ticks{}, "-log"); err != nil {
-			return err
+	for _, pair := range pairs {
+		for _, y := range []*ysel{yselNsPerOp, yselAllocs, yselAlocKB, yselMBps} {
+			if err := genheatmaps(plotName, path, bopts, results, pair[0], pair[1], y); err != nil {
+				return err
+			}
 		}
-
-		if err := genplots(plotName, path, bopts, results, ixsel, yselMBps, plot.LinearScale{}, plot.DefaultTicks{}, ""); err != nil {
-			return err
+		if len(normalized) > 0 {
+			if err := genheatmaps(plotName, path, bopts, normalized, pair[0], pair[1], speedup); err != nil {
+				return err
+			}
 		}
 
-		if err := genplots(plotName, path, bopts, results, ixsel, yselMBps, ZeroLogScale{}, Log2Ticks{}, "-log"); err != nil {
-			return err
+		for _, axes := range [][2]*xsel{pair, {pair[1], pair[0]}} {
+			for _, yp := range yplots {
+				if err := gensmallmultiples(plotName, path, bopts, results, axes[0], axes[1], yp.y, yp.scale, yp.marker, yp.suffix); err != nil {
+					return err
+				}
+			}
+			if len(normalized) > 0 {
+				if err := gensmallmultiples(plotName, path, bopts, normalized, axes[0], axes[1], speedup, ZeroLogScale{}, RatioTicks{}, ""); err != nil {
+					return err
+				}
+			}
 		}
 	}
 	return nil
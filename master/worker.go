@@ -1,6 +1,8 @@
 package master
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -10,6 +12,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 
@@ -80,14 +83,7 @@ func BuildBatch(new func() (*BatchSpec, error), cont bool) (*BatchSpec, error) {
 		return nil, ErrExists
 	} else if !os.IsNotExist(err) && cont {
 		log.Println("Continuing from existing results")
-		b, err := ioutil.ReadFile("results.json")
-		if err != nil {
-			return nil, err
-		}
-
-		var s BatchSpec
-		err = json.Unmarshal(b, &s)
-		return &s, err
+		return LoadBatch("results.json")
 	}
 
 	nspec, err := new()
@@ -98,6 +94,20 @@ func BuildBatch(new func() (*BatchSpec, error), cont bool) (*BatchSpec, error) {
 	return nspec, nspec.save()
 }
 
+// LoadBatch loads a BatchSpec saved to a results.json file
+func LoadBatch(path string) (*BatchSpec, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s BatchSpec
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &s, nil
+}
+
 // wuQueue implements a chan based FIFO queue
 func wuQueue() struct {
 	in  chan<- workUnit
@@ -142,8 +152,9 @@ func wuQueue() struct {
 }
 
 type result struct {
-	b   *parse.Benchmark
-	err error
+	b    *parse.Benchmark
+	meta map[string]string
+	err  error
 
 	instanceType string
 	wu           workUnit
@@ -175,6 +186,10 @@ func (b *BatchSpec) save() error {
 }
 
 func (b *BatchSpec) Start() error {
+	if err := b.validate(); err != nil {
+		return err
+	}
+
 	ctx, done := context.WithCancel(context.Background())
 	defer done()
 
@@ -192,7 +207,7 @@ func (b *BatchSpec) Start() error {
 		for id, worker := range workers {
 			log.Printf("Starting worker %s-%d", itype, id)
 			wg.Add(1)
-			go func(itype string, worker Worker) {
+			go func(itype string, id int, worker Worker) {
 				defer wg.Done()
 				for {
 					select {
@@ -201,10 +216,11 @@ func (b *BatchSpec) Start() error {
 							log.Printf("Stopping worker %s-%d", itype, id)
 							return
 						}
-						b, err := worker.run(b.Datastores[wu.ds], b.Jobs[itype][wu.series], wu.point)
+						b, meta, err := worker.run(b.Datastores[wu.ds], b.Jobs[itype][wu.series], wu.point)
 						results <- result{
-							b:   b,
-							err: err,
+							b:    b,
+							meta: meta,
+							err:  err,
 
 							instanceType: itype,
 							wu:           wu,
@@ -214,7 +230,7 @@ func (b *BatchSpec) Start() error {
 						return
 					}
 				}
-			}(itype, worker)
+			}(itype, id, worker)
 		}
 	}
 
@@ -222,6 +238,9 @@ func (b *BatchSpec) Start() error {
 	for itype, srss := range b.Jobs {
 		for dsid, ds := range b.Datastores {
 			for sid, series := range srss {
+				if !series.runsOn(ds) {
+					continue
+				}
 				for _, point := range series.todo(ds.Name) {
 					queues[itype].in <- workUnit{
 						series: sid,
@@ -244,7 +263,7 @@ func (b *BatchSpec) Start() error {
 		case result, ok := <-results:
 			if !ok {
 				log.Printf("Stopping result collection (results chan closed)")
-				return b.standardPlots()
+				return b.Plot()
 			}
 
 			if result.err != nil {
@@ -254,7 +273,9 @@ func (b *BatchSpec) Start() error {
 
 			series := b.Jobs[result.instanceType][result.wu.series]
 			series.lk.Lock()
-			series.Results[b.Datastores[result.wu.ds].Name][result.wu.point] = result.b
+			desc := series.Opts[result.wu.point].Descriptor()
+			series.Results[b.Datastores[result.wu.ds].Name][desc] = result.b
+			series.setMeta(b.Datastores[result.wu.ds].Name, desc, result.meta)
 
 			series.lk.Unlock()
 			if err := b.save(); err != nil {
@@ -263,12 +284,9 @@ func (b *BatchSpec) Start() error {
 
 		case <-ctx.Done():
 			log.Printf("Stopping result collection (ctx expired)")
-			return b.standardPlots()
+			return b.Plot()
 		}
 	}
-
-	// TODO: port/call func (s *Series) standardPlots()
-	return nil
 }
 
 func (w *Worker) log(f string, v ...interface{}) {
@@ -287,20 +305,22 @@ type Piper interface {
 	TeeReader(r io.Reader, w io.Writer) io.Reader
 }
 
-func (w *Worker) run(ids options.WorkerDatastore, series *Series, point int) (*parse.Benchmark, error) {
+// run runs a single point of a series, returning its result along with
+// benchfmt configuration lines printed by the worker
+func (w *Worker) run(ids options.WorkerDatastore, series *Series, point int) (*parse.Benchmark, map[string]string, error) {
 	init, ok := env.Handlers[w.Type]
 	if !ok {
-		return nil, fmt.Errorf("unknown remote type: %s", w.Type)
+		return nil, nil, fmt.Errorf("unknown remote type: %s", w.Type)
 	}
 
 	env, err := init(w.Spec)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var ds options.WorkerDatastore
 	if err := clone(&ids, &ds); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	for n, t := range ds.Params {
@@ -316,20 +336,20 @@ func (w *Worker) run(ids options.WorkerDatastore, series *Series, point int) (*p
 	}
 	specJson, err := json.Marshal(&spec)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if err := env.WriteFile("spec.json", specJson, 0644); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if err := env.CopyFile(ds.Scripts.Pre[0], "prerun.sh", 0755); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if err := env.CopyFile(ds.Scripts.Post[0], "postrun.sh", 0755); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if err := env.CopyFile(workerBin, "worker.test", 0755); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if len(ds.Scripts.Pre) != 0 {
@@ -337,7 +357,7 @@ func (w *Worker) run(ids options.WorkerDatastore, series *Series, point int) (*p
 
 		run := env.Cmd("/usr/bin/env", []string{"bash", "-c", "./prerun.sh " + w.replaceVars(ds.Scripts.Pre)[1]}, os.Stdout, os.Stdout)
 		if err := run(); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
@@ -362,9 +382,14 @@ func (w *Worker) run(ids options.WorkerDatastore, series *Series, point int) (*p
 
 	w.log("parse")
 
-	bset, err := parse.ParseSet(sout)
+	out, err := ioutil.ReadAll(sout)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	bset, err := parse.ParseSet(bytes.NewReader(out))
+	if err != nil {
+		return nil, nil, err
 	}
 
 	w.log("wait")
@@ -374,29 +399,59 @@ func (w *Worker) run(ids options.WorkerDatastore, series *Series, point int) (*p
 		log.Printf("running post-run script for datastore %s: %s", ds.Name, w.replaceVars(ds.Scripts.Post))
 		run := env.Cmd("/usr/bin/env", []string{"bash", "-c", "./postrun.sh " + w.replaceVars(ds.Scripts.Post)[1]}, os.Stdout, os.Stdout)
 		if err := run(); err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+	}
+
+	conf := parseConfig(out)
+	for _, k := range []string{"lost-writes", "corrupted-writes"} {
+		if n := conf[k]; n != "" && n != "0" {
+			w.log("%s: %s %s of %s acknowledged", ds.Name, n, k, conf["acked-writes"])
 		}
 	}
+	if n := conf["integrity-errors"]; n != "" && n != "0" {
+		return nil, conf, fmt.Errorf("%s returned %s wrong values", ds.Name, n)
+	}
+	if conf["unsupported"] != "" {
+		w.log("%s doesn't support %s, recording as unsupported", ds.Name, conf["unsupported"])
+		return nil, conf, nil
+	}
 
 	if len(bset) != 1 {
-		return nil, fmt.Errorf("unexpected bench count: %d", len(bset))
+		return nil, nil, fmt.Errorf("unexpected bench count: %d", len(bset))
 	}
 
 	for _, b := range bset {
 		if len(b) != 1 {
-			return nil, errors.New("unexpected bench len")
+			return nil, nil, errors.New("unexpected bench len")
 		}
 
-		return b[0], nil
+		return b[0], conf, nil
 	}
 
 	panic("shouldn't be here")
 }
 
-func convertFlat(i map[string]map[int]*parse.Benchmark) map[string]map[int][]*parse.Benchmark {
-	out := map[string]map[int][]*parse.Benchmark{}
+// configLine matches benchfmt configuration lines, like "goos: linux"
+var configLine = regexp.MustCompile(`^([a-z][^\s:]*):\s+(.*)$`)
+
+// parseConfig collects benchfmt configuration lines from benchmark output
+func parseConfig(out []byte) map[string]string {
+	conf := map[string]string{}
+
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		if m := configLine.FindStringSubmatch(s.Text()); m != nil {
+			conf[m[1]] = m[2]
+		}
+	}
+	return conf
+}
+
+func convertFlat(i map[string]map[string]*parse.Benchmark) map[string]map[string][]*parse.Benchmark {
+	out := map[string]map[string][]*parse.Benchmark{}
 	for k, a := range i {
-		out[k] = map[int][]*parse.Benchmark{}
+		out[k] = map[string][]*parse.Benchmark{}
 		for n, b := range a {
 			out[k][n] = []*parse.Benchmark{b}
 		}
@@ -404,7 +459,9 @@ func convertFlat(i map[string]map[int]*parse.Benchmark) map[string]map[int][]*pa
 	return out
 }
 
-func (b *BatchSpec) standardPlots() error {
+// Plot writes plots of all results under x_plots, in formats selected with
+// SetPlotFormats, along with x_plots/index.html linking them
+func (b *BatchSpec) Plot() error {
 	os.Mkdir("x_plots", 0755)
 
 	for itype, srs := range b.Jobs {
@@ -412,8 +469,12 @@ func (b *BatchSpec) standardPlots() error {
 		os.Mkdir("x_plots/"+itype+"/combined", 0755)
 
 		for _, s := range srs {
+			base, err := b.baseline(itype, s)
+			if err != nil {
+				return err
+			}
 
-			if err := benchPlots(s.PlotName, "x_plots/"+itype+"/combined/", s.Opts, convertFlat(s.Results)); err != nil {
+			if err := benchPlots(s.PlotName, "x_plots/"+itype+"/combined/", s.Opts, convertFlat(s.Results), base); err != nil {
 				return err
 			}
 		}
@@ -422,20 +483,28 @@ func (b *BatchSpec) standardPlots() error {
 	for itype, inst := range b.Jobs {
 
 		for _, series := range inst {
+			base, err := b.baseline(itype, series)
+			if err != nil {
+				return err
+			}
+
 			// tag -> ds_name
-			tagged := map[string]map[string]map[int]*parse.Benchmark{}
+			tagged := map[string]map[string]map[string]*parse.Benchmark{}
 			// type -> ds_name
-			typed := map[string]map[string]map[int]*parse.Benchmark{}
+			typed := map[string]map[string]map[string]*parse.Benchmark{}
 
 			for _, ds := range b.Datastores {
+				if !series.runsOn(ds) {
+					continue
+				}
 				if _, ok := typed[ds.Type]; !ok {
-					typed[ds.Type] = map[string]map[int]*parse.Benchmark{}
+					typed[ds.Type] = map[string]map[string]*parse.Benchmark{}
 				}
 				typed[ds.Type][ds.Name] = series.Results[ds.Name]
 
 				for _, tag := range ds.Tags {
 					if _, ok := tagged[tag]; !ok {
-						tagged[tag] = map[string]map[int]*parse.Benchmark{}
+						tagged[tag] = map[string]map[string]*parse.Benchmark{}
 					}
 					tagged[tag][ds.Name] = series.Results[ds.Name]
 				}
@@ -444,7 +513,7 @@ func (b *BatchSpec) standardPlots() error {
 			for t, res := range tagged {
 				os.Mkdir("x_plots/"+itype+"/tag-"+t, 0755)
 
-				if err := benchPlots(series.PlotName, "x_plots/"+itype+"/tag-"+t+"/", series.Opts, convertFlat(res)); err != nil {
+				if err := benchPlots(series.PlotName, "x_plots/"+itype+"/tag-"+t+"/", series.Opts, convertFlat(res), base); err != nil {
 					return err
 				}
 			}
@@ -452,18 +521,18 @@ func (b *BatchSpec) standardPlots() error {
 			for t, res := range typed {
 				os.Mkdir("x_plots/"+itype+"/ds-"+t, 0755)
 
-				if err := benchPlots(series.PlotName, "x_plots/"+itype+"/ds-"+t+"/", series.Opts, convertFlat(res)); err != nil {
+				if err := benchPlots(series.PlotName, "x_plots/"+itype+"/ds-"+t+"/", series.Opts, convertFlat(res), base); err != nil {
 					return err
 				}
 			}
 
 			os.Mkdir("x_plots/"+itype+"/tag--avg/", 0755)
-			if err := benchPlots(series.PlotName, "x_plots/"+itype+"/tag--avg/", series.Opts, series.doAvg(tagged)); err != nil {
+			if err := benchPlots(series.PlotName, "x_plots/"+itype+"/tag--avg/", series.Opts, series.doAvg(tagged), base.avg(tagged)); err != nil {
 				return err
 			}
 
 			os.Mkdir("x_plots/"+itype+"/ds--avg/", 0755)
-			if err := benchPlots(series.PlotName, "x_plots/"+itype+"/ds--avg/", series.Opts, series.doAvg(typed)); err != nil {
+			if err := benchPlots(series.PlotName, "x_plots/"+itype+"/ds--avg/", series.Opts, series.doAvg(typed), base.avg(typed)); err != nil {
 				return err
 			}
 		}
@@ -471,7 +540,7 @@ func (b *BatchSpec) standardPlots() error {
 
 	plotWg.Wait()
 
-	return nil
+	return writeIndex("x_plots")
 }
 
 // HACK!
@@ -0,0 +1,104 @@
+package options
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// descEscaper escapes characters separating descriptor fields, ParseDescriptor
+// undoes it with url.PathUnescape
+var descEscaper = strings.NewReplacer("%", "%25", ",", "%2C", "=", "%3D")
+
+// alwaysDescribed fields are part of every descriptor, even when zero
+var alwaysDescribed = map[string]bool{"pre": true, "size": true, "batch": true}
+
+// Descriptor returns a canonical description of all options, which
+// ParseDescriptor turns back into the same options. Fields are listed as
+// `name=value`, comma separated, in declaration order, named by their `desc`
+// tag and left out when zero, except for pre, size and batch:
+//
+//	pre=65536,size=4096,batch=64,dist=zipfian,txn=4
+//
+// Options with the same descriptor are equal, so descriptors can key results.
+func (opt BenchOptions) Descriptor() string {
+	v := reflect.ValueOf(opt)
+	t := v.Type()
+
+	var parts []string
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("desc")
+		f := v.Field(i)
+		if f.IsZero() && !alwaysDescribed[name] {
+			continue
+		}
+
+		var val string
+		switch f.Kind() {
+		case reflect.Int:
+			val = strconv.FormatInt(f.Int(), 10)
+		case reflect.Float64:
+			val = strconv.FormatFloat(f.Float(), 'g', -1, 64)
+		case reflect.String:
+			val = descEscaper.Replace(f.String())
+		default:
+			panic(fmt.Sprintf("can't describe %s of type %s", t.Field(i).Name, f.Type()))
+		}
+		parts = append(parts, name+"="+val)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseDescriptor parses options described by BenchOptions.Descriptor,
+// fields can be listed in any order, missing ones are zero
+func ParseDescriptor(desc string) (BenchOptions, error) {
+	var opt BenchOptions
+	v := reflect.ValueOf(&opt).Elem()
+	t := v.Type()
+
+	fields := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		fields[t.Field(i).Tag.Get("desc")] = i
+	}
+
+	seen := map[string]bool{}
+	for _, part := range strings.Split(desc, ",") {
+		name, raw, ok := strings.Cut(part, "=")
+		if !ok {
+			return BenchOptions{}, fmt.Errorf("descriptor '%s': expected name=value, got '%s'", desc, part)
+		}
+		i, ok := fields[name]
+		if !ok {
+			return BenchOptions{}, fmt.Errorf("descriptor '%s': unknown option '%s'", desc, name)
+		}
+		if seen[name] {
+			return BenchOptions{}, fmt.Errorf("descriptor '%s': option '%s' repeated", desc, name)
+		}
+		seen[name] = true
+
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.Int:
+			n, err := strconv.ParseInt(raw, 10, 0)
+			if err != nil {
+				return BenchOptions{}, fmt.Errorf("descriptor '%s': %s: %w", desc, name, err)
+			}
+			f.SetInt(n)
+		case reflect.Float64:
+			n, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return BenchOptions{}, fmt.Errorf("descriptor '%s': %s: %w", desc, name, err)
+			}
+			f.SetFloat(n)
+		case reflect.String:
+			s, err := url.PathUnescape(raw)
+			if err != nil {
+				return BenchOptions{}, fmt.Errorf("descriptor '%s': %s: %w", desc, name, err)
+			}
+			f.SetString(s)
+		}
+	}
+	return opt, nil
+}
@@ -0,0 +1,94 @@
+package options
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDescriptorRoundTrip(t *testing.T) {
+	cases := []BenchOptions{
+		{},
+		{PrimeRecordCount: 1 << 16, RecordSize: 1 << 12, BatchSize: 64},
+		{PrimeRecordCount: 1 << 16, RecordSize: 1 << 12, BatchSize: 64, HitRatio: NoHits, DsParam: "BloomFilterBits", DsParamValue: 0},
+		{
+			PrimeRecordCount: 1,
+			RecordSize:       2,
+			BatchSize:        3,
+			KeyDistribution:  KeyDistHotspot,
+			ZipfTheta:        0.99,
+			HotspotFraction:  1.0 / 3,
+			KeyFormat:        KeyFormatCid,
+			KeySize:          40,
+			KeyPrefix:        "/blocks,a=b%2C",
+			KeyDepth:         2,
+			CompressionRatio: 0.5,
+			UpdateRecordSize: 4,
+			HitRatio:         1.0 / 64,
+			Verify:           VerifyOnRead,
+			TxnSize:          4,
+			Concurrency:      8,
+			DsParam:          "BlockCacheCapacity",
+			DsParamValue:     1 << 20,
+		},
+	}
+
+	for _, opt := range cases {
+		desc := opt.Descriptor()
+		parsed, err := ParseDescriptor(desc)
+		if err != nil {
+			t.Fatalf("%s: %s", desc, err)
+		}
+		if !reflect.DeepEqual(parsed, opt) {
+			t.Errorf("%s: parsed as %+v, expected %+v", desc, parsed, opt)
+		}
+	}
+}
+
+func TestDescriptorFormat(t *testing.T) {
+	opt := BenchOptions{PrimeRecordCount: 1 << 16, RecordSize: 1 << 12, BatchSize: 64, KeyDistribution: KeyDistZipfian, HitRatio: NoHits, KeyPrefix: "/blocks"}
+	expect := "pre=65536,size=4096,batch=64,dist=zipfian,prefix=/blocks,hit=-1"
+	if d := opt.Descriptor(); d != expect {
+		t.Errorf("got %s, expected %s", d, expect)
+	}
+
+	reordered, err := ParseDescriptor("hit=-1,dist=zipfian,prefix=/blocks,batch=64,size=4096,pre=65536")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reordered != opt {
+		t.Errorf("parsed reordered descriptor as %+v", reordered)
+	}
+}
+
+func TestDescriptorTags(t *testing.T) {
+	seen := map[string]string{}
+	typ := reflect.TypeOf(BenchOptions{})
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		name := f.Tag.Get("desc")
+		if name == "" {
+			t.Errorf("%s has no desc tag", f.Name)
+			continue
+		}
+		if other, ok := seen[name]; ok {
+			t.Errorf("%s and %s are both described as %s", other, f.Name, name)
+		}
+		seen[name] = f.Name
+	}
+}
+
+func TestParseDescriptorErrors(t *testing.T) {
+	for _, desc := range []string{
+		"",
+		"pre=1,size",
+		"pre=1,nope=2",
+		"pre=1,pre=2",
+		"pre=x",
+		"hit=half",
+		"prefix=%zz",
+	} {
+		if _, err := ParseDescriptor(desc); err == nil {
+			t.Errorf("'%s' parsed without error", desc)
+		}
+	}
+}
@@ -13,6 +13,14 @@ type WorkerDatastore struct {
 	}
 
 	Params map[string]interface{} //ds specific
+
+	Wrappers []WrapperSpec // applied in order, the first one wraps the datastore
+}
+
+// WrapperSpec selects a go-datastore wrapper layered over a candidate
+type WrapperSpec struct {
+	Type   string
+	Params map[string]interface{}
 }
 
 type TestSpec struct {
@@ -23,63 +31,130 @@ type TestSpec struct {
 }
 
 type BenchOptions struct {
-	PrimeRecordCount int // number of records in the datastore before the test
-	RecordSize       int // size of one record
-	BatchSize        int // size of the batch, only applies to batched operations
+	PrimeRecordCount int `desc:"pre"`   // number of records in the datastore before the test
+	RecordSize       int `desc:"size"`  // size of one record
+	BatchSize        int `desc:"batch"` // size of the batch, only applies to batched operations
+
+	KeyDistribution string  `desc:"dist"`    // key access pattern, one of KeyDist*, defaults to KeyDistSequential
+	ZipfTheta       float64 `desc:"theta"`   // skew of zipfian and latest distributions, (0, 1), defaults to 0.99
+	HotspotFraction float64 `desc:"hotspot"` // fraction of keys receiving 1-HotspotFraction of accesses, defaults to 0.2
+
+	KeyFormat string `desc:"keys"`    // one of KeyFormat*, defaults to KeyFormatHex
+	KeySize   int    `desc:"keysize"` // length of hex keys, defaults to 32
+	KeyPrefix string `desc:"prefix"`  // namespace all keys are put under, e.g. /blocks, flatfs needs a mount wrapper for it
+	KeyDepth  int    `desc:"depth"`   // number of 2 character namespaces keys are nested in, not supported by flatfs
+
+	CompressionRatio float64 `desc:"comp"` // approximate compressibility of values, 1 (default) for random data
+
+	UpdateRecordSize int `desc:"usize"` // size of values overwriting records in update benchmarks, defaults to RecordSize
+
+	HitRatio float64 `desc:"hit"` // fraction of lookups for present keys, 0 for bench default, NoHits for none
+
+	Verify string `desc:"verify"` // one of Verify*, checks values returned by get benchmarks, none if empty
+
+	TxnSize     int `desc:"txn"`  // keys read or written per transaction, only applies to transaction benchmarks, defaults to 1
+	Concurrency int `desc:"conc"` // goroutines running operations, only applies to transaction benchmarks, defaults to 1
+
+	DsParam      string  `desc:"dsparam"` // WorkerDatastore.Params entry overridden with DsParamValue, for sweeping datastore params
+	DsParamValue float64 `desc:"dsvalue"` // value of DsParam
 }
 
+// NoHits is a HitRatio making all lookups miss
+const NoHits = -1
+
+const (
+	KeyDistSequential = "sequential" // keys accessed in insertion order
+	KeyDistUniform    = "uniform"
+	KeyDistZipfian    = "zipfian"
+	KeyDistHotspot    = "hotspot"
+	KeyDistLatest     = "latest" // zipfian, skewed towards recently inserted keys
+)
+
+const (
+	KeyFormatHex   = "hex"   // random uppercase hex string
+	KeyFormatCid   = "cid"   // base32 multihash, as stored by go-ipfs blockstore
+	KeyFormatCidV1 = "cidv1" // base32 CIDv1, as stored by go-ipfs blockstore before 0.12
+)
+
+// Verification modes of get benchmarks, records are written with contents
+// derived from their keys when set
+const (
+	VerifyAfter  = "after"   // all records are read back and checked after the timed part
+	VerifyOnRead = "on-read" // values are checked as they are read, with the timer stopped
+)
+
+// Sync modes datastores can be opened with
+const (
+	SyncNone     = "none"      // writes aren't synced
+	SyncPerOp    = "per-op"    // every write, including batch commits, is durable once it returns
+	SyncPerBatch = "per-batch" // batch commits are durable once they return, single writes aren't
+)
+
+// TestDesc returns a short sub-benchmark name, it leaves out some options and
+// can't be parsed, use Descriptor to identify options
 func (opt BenchOptions) TestDesc() string {
-	return fmt.Sprintf("pre=%d-size=%d-batch=%d", opt.PrimeRecordCount, opt.RecordSize, opt.BatchSize)
+	desc := fmt.Sprintf("pre=%d-size=%d-batch=%d", opt.PrimeRecordCount, opt.RecordSize, opt.BatchSize)
+	if opt.KeyDistribution != "" {
+		desc += "-dist=" + opt.KeyDistribution
+	}
+	if opt.KeyFormat != "" {
+		desc += "-keys=" + opt.KeyFormat
+	}
+	if opt.UpdateRecordSize != 0 {
+		desc += fmt.Sprintf("-usize=%d", opt.UpdateRecordSize)
+	}
+	if opt.HitRatio != 0 {
+		desc += fmt.Sprintf("-hit=%g", opt.HitRatio)
+	}
+	if opt.CompressionRatio != 0 {
+		desc += fmt.Sprintf("-comp=%g", opt.CompressionRatio)
+	}
+	if opt.Verify != "" {
+		desc += "-verify=" + opt.Verify
+	}
+	if opt.TxnSize != 0 {
+		desc += fmt.Sprintf("-txn=%d", opt.TxnSize)
+	}
+	if opt.Concurrency != 0 {
+		desc += fmt.Sprintf("-conc=%d", opt.Concurrency)
+	}
+	if opt.DsParam != "" {
+		desc += fmt.Sprintf("-%s=%g", opt.DsParam, opt.DsParamValue)
+	}
+	return desc
 }
 
-func OptionsRange2pow(start, end BenchOptions, countPerAxis int) []BenchOptions {
-	res := []BenchOptions{start}
-
-	if start == end {
-		return res
+// DatastoreParams returns params with the DsParam override applied, params
+// aren't modified
+func (opt BenchOptions) DatastoreParams(params map[string]interface{}) map[string]interface{} {
+	if opt.DsParam == "" {
+		return params
 	}
 
-	axis := make([]float64, countPerAxis)
-	maxN := 1 << uint(countPerAxis)
-	for i := 0; i < countPerAxis-1; i++ {
-		axis[i] = float64(uint(1)<<uint(i+1)) / float64(maxN)
+	out := make(map[string]interface{}, len(params)+1)
+	for k, v := range params {
+		out[k] = v
 	}
-	axis[countPerAxis-1] = 1
+	out[opt.DsParam] = opt.DsParamValue
+	return out
+}
 
+// OptionsRange2pow sweeps fields differing between start and end
+// (PrimeRecordCount, RecordSize, BatchSize, in product) over countPerAxis
+// points spaced evenly on a log scale, from start to end.
+//
+// Deprecated: use Sweep, which can sweep any field with any spacing.
+func OptionsRange2pow(start, end BenchOptions, countPerAxis int) []BenchOptions {
+	var axes []Sweep
 	if start.PrimeRecordCount != end.PrimeRecordCount {
-		bRes := res[:]
-		res = make([]BenchOptions, 0, countPerAxis*len(bRes))
-		for _, opt := range bRes {
-			for _, scale := range axis {
-				opt.PrimeRecordCount = int(float64(end.PrimeRecordCount-start.PrimeRecordCount)*scale) + start.PrimeRecordCount
-				res = append(res, opt)
-			}
-		}
-
+		axes = append(axes, logSpace("PrimeRecordCount", float64(start.PrimeRecordCount), float64(end.PrimeRecordCount), countPerAxis))
 	}
 	if start.RecordSize != end.RecordSize {
-		bRes := res[:]
-		res = make([]BenchOptions, 0, countPerAxis*len(bRes))
-		for _, opt := range bRes {
-			for _, scale := range axis {
-				opt.RecordSize = int(float64(end.RecordSize-start.RecordSize)*scale) + start.RecordSize
-				res = append(res, opt)
-			}
-		}
-
+		axes = append(axes, logSpace("RecordSize", float64(start.RecordSize), float64(end.RecordSize), countPerAxis))
 	}
-
 	if start.BatchSize != end.BatchSize {
-		bRes := res[:]
-		res = make([]BenchOptions, 0, countPerAxis*len(bRes))
-		for _, opt := range bRes {
-			for _, scale := range axis {
-				opt.BatchSize = int(float64(end.BatchSize-start.BatchSize)*scale) + start.BatchSize
-				res = append(res, opt)
-			}
-		}
-
+		axes = append(axes, logSpace("BatchSize", float64(start.BatchSize), float64(end.BatchSize), countPerAxis))
 	}
 
-	return res
+	return Product(axes...).MustOptions(start)
 }
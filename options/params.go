@@ -0,0 +1,189 @@
+package options
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Param types
+const (
+	ParamString = "string"
+	ParamBool   = "bool"
+	ParamInt    = "int"
+	ParamFloat  = "float"
+)
+
+// ParamSpec describes one param accepted by a candidate or wrapper
+type ParamSpec struct {
+	Name     string
+	Type     string
+	Default  interface{} `json:",omitempty"`
+	Required bool        `json:",omitempty"`
+	Doc      string      `json:",omitempty"`
+}
+
+// CandidateInfo describes a datastore available in a worker binary
+type CandidateInfo struct {
+	Name   string
+	Params []ParamSpec
+}
+
+// WorkerInfo describes datastores and wrappers available in a worker binary
+type WorkerInfo struct {
+	Datastores []CandidateInfo
+	Wrappers   []CandidateInfo
+}
+
+// ParamSchema describes exported fields of a params struct, including fields
+// of embedded structs. Field values of defaults are used as parameter
+// defaults. Fields can be tagged with `param:"required"` and `doc:"..."`.
+func ParamSchema(defaults interface{}) []ParamSpec {
+	if defaults == nil {
+		return nil
+	}
+
+	return paramSchema(reflect.Indirect(reflect.ValueOf(defaults)))
+}
+
+func paramSchema(v reflect.Value) []ParamSpec {
+	t := v.Type()
+
+	out := make([]ParamSpec, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			out = append(out, paramSchema(v.Field(i))...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		spec := ParamSpec{
+			Name:     f.Name,
+			Type:     paramType(f.Type.Kind()),
+			Required: f.Tag.Get("param") == "required",
+			Doc:      f.Tag.Get("doc"),
+		}
+		if spec.Type == "" {
+			panic(fmt.Sprintf("options: unsupported param type %s of %s.%s", f.Type, t.Name(), f.Name))
+		}
+		if !v.Field(i).IsZero() {
+			spec.Default = v.Field(i).Interface()
+		}
+
+		out = append(out, spec)
+	}
+	return out
+}
+
+func paramType(k reflect.Kind) string {
+	switch k {
+	case reflect.String:
+		return ParamString
+	case reflect.Bool:
+		return ParamBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return ParamInt
+	case reflect.Float32, reflect.Float64:
+		return ParamFloat
+	default:
+		return ""
+	}
+}
+
+// CheckParams validates params against a schema, rejecting unknown, mistyped
+// and missing required params
+func CheckParams(schema []ParamSpec, params map[string]interface{}) error {
+	known := map[string]ParamSpec{}
+	for _, s := range schema {
+		known[s.Name] = s
+	}
+
+	var errs []string
+	for name, v := range params {
+		s, ok := known[name]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("unknown param '%s'", name))
+			continue
+		}
+		if err := checkParam(s, v); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	for _, s := range schema {
+		if _, ok := params[s.Name]; s.Required && !ok {
+			errs = append(errs, fmt.Sprintf("missing required param '%s'", s.Name))
+		}
+	}
+
+	if len(errs) != 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("invalid params: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func checkParam(s ParamSpec, v interface{}) error {
+	ok := false
+	switch s.Type {
+	case ParamString:
+		_, ok = v.(string)
+	case ParamBool:
+		_, ok = v.(bool)
+	case ParamInt:
+		var f float64
+		f, ok = number(v)
+		ok = ok && f == math.Trunc(f)
+	case ParamFloat:
+		_, ok = number(v)
+	}
+
+	if !ok {
+		return fmt.Errorf("param '%s' must be %s, got %T(%v)", s.Name, s.Type, v, v)
+	}
+	return nil
+}
+
+// number accepts values decoded from json (float64) as well as ints set in go
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// DecodeParams validates params against the schema of the struct out points
+// to, and sets its fields. Fields without a param keep their values.
+func DecodeParams(params map[string]interface{}, out interface{}) error {
+	if err := CheckParams(ParamSchema(out), params); err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(out).Elem()
+	for name, p := range params {
+		f := v.FieldByName(name)
+		switch paramType(f.Kind()) {
+		case ParamInt:
+			n, _ := number(p)
+			f.SetInt(int64(n))
+		case ParamFloat:
+			n, _ := number(p)
+			f.SetFloat(n)
+		default:
+			f.Set(reflect.ValueOf(p))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package options
+
+import (
+	"strings"
+	"testing"
+)
+
+type testDisk struct {
+	DataDir string `param:"required" doc:"data"`
+	Sync    bool
+}
+
+type testParams struct {
+	testDisk
+
+	Size  int
+	Ratio float64
+	Mode  string
+}
+
+func TestParamSchema(t *testing.T) {
+	schema := ParamSchema(testParams{Size: 10})
+
+	expect := []ParamSpec{
+		{Name: "DataDir", Type: ParamString, Required: true, Doc: "data"},
+		{Name: "Sync", Type: ParamBool},
+		{Name: "Size", Type: ParamInt, Default: 10},
+		{Name: "Ratio", Type: ParamFloat},
+		{Name: "Mode", Type: ParamString},
+	}
+
+	if len(schema) != len(expect) {
+		t.Fatalf("expected %d params, got %d", len(expect), len(schema))
+	}
+	for i := range expect {
+		if schema[i] != expect[i] {
+			t.Errorf("param %d: expected %+v, got %+v", i, expect[i], schema[i])
+		}
+	}
+}
+
+func TestDecodeParams(t *testing.T) {
+	p := testParams{Size: 10, Mode: "a"}
+	err := DecodeParams(map[string]interface{}{
+		"DataDir": "/tmp",
+		"Sync":    true,
+		"Ratio":   0.5,
+		"Size":    float64(20), // as decoded from json
+	}, &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.DataDir != "/tmp" || !p.Sync || p.Ratio != 0.5 || p.Size != 20 || p.Mode != "a" {
+		t.Errorf("unexpected params: %+v", p)
+	}
+}
+
+func TestDecodeParamsInvalid(t *testing.T) {
+	cases := map[string]map[string]interface{}{
+		"missing required param 'DataDir'": {},
+		"unknown param 'Snyc'":             {"DataDir": "/tmp", "Snyc": true},
+		"param 'Sync' must be bool":        {"DataDir": "/tmp", "Sync": "true"},
+		"param 'Size' must be int":         {"DataDir": "/tmp", "Size": 1.5},
+	}
+
+	for expect, params := range cases {
+		var p testParams
+		err := DecodeParams(params, &p)
+		if err == nil || !strings.Contains(err.Error(), expect) {
+			t.Errorf("expected error containing %q, got %v", expect, err)
+		}
+	}
+}
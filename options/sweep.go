@@ -0,0 +1,216 @@
+package options
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// setting assigns a value to a BenchOptions field
+type setting struct {
+	field string
+	value interface{}
+}
+
+// Sweep is an ordered list of points in the option space, each setting some
+// BenchOptions fields. Sweeps are built per axis with Values, Linear,
+// Geometric, Log2 and Log10, and combined with Product and Zip; Options
+// applies them to base options.
+type Sweep struct {
+	points [][]setting
+	err    error
+}
+
+func axis(field string, values []interface{}) Sweep {
+	points := make([][]setting, len(values))
+	for i, v := range values {
+		points[i] = []setting{{field: field, value: v}}
+	}
+	return Sweep{points: points}
+}
+
+// Values sweeps field over explicit values, numbers or strings depending on
+// the field type
+func Values(field string, values ...interface{}) Sweep {
+	return axis(field, values)
+}
+
+// Linear sweeps field over count evenly spaced values from start to end
+func Linear(field string, start, end float64, count int) Sweep {
+	if count < 1 {
+		return Sweep{err: fmt.Errorf("%s: linear sweep needs at least one point, got %d", field, count)}
+	}
+
+	values := make([]interface{}, count)
+	for i := range values {
+		if count == 1 {
+			values[i] = start
+			continue
+		}
+		values[i] = start + (end-start)*float64(i)/float64(count-1)
+	}
+	return axis(field, values)
+}
+
+// Geometric sweeps field from start, multiplying by factor while below end,
+// end is always the last point
+func Geometric(field string, start, end, factor float64) Sweep {
+	if start <= 0 || end < start || factor <= 1 {
+		return Sweep{err: fmt.Errorf("%s: geometric sweep needs 0 < start <= end and factor > 1, got %g, %g, %g", field, start, end, factor)}
+	}
+
+	var values []interface{}
+	for v := start; v < end && !closeTo(v, end); v *= factor {
+		values = append(values, v)
+	}
+	return axis(field, append(values, end))
+}
+
+// Log2 sweeps field over start, 2*start, 4*start, ... up to end
+func Log2(field string, start, end float64) Sweep {
+	return Geometric(field, start, end, 2)
+}
+
+// Log10 sweeps field over start, 10*start, 100*start, ... up to end
+func Log10(field string, start, end float64) Sweep {
+	return Geometric(field, start, end, 10)
+}
+
+// logSpace sweeps field over count values from start to end, evenly spaced
+// on a log scale
+func logSpace(field string, start, end float64, count int) Sweep {
+	if start <= 0 || end <= 0 {
+		return Sweep{err: fmt.Errorf("%s: log sweep needs positive bounds, got %g, %g", field, start, end)}
+	}
+	if count < 2 {
+		return Values(field, start)
+	}
+
+	values := make([]interface{}, count)
+	for i := range values {
+		values[i] = start * math.Pow(end/start, float64(i)/float64(count-1))
+	}
+	return axis(field, values)
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// Product combines sweeps into their cartesian product, the first sweep
+// varies slowest. Product of no sweeps has a single point keeping base
+// options.
+func Product(sweeps ...Sweep) Sweep {
+	out := Sweep{points: [][]setting{nil}}
+	for _, s := range sweeps {
+		if s.err != nil {
+			return s
+		}
+
+		points := make([][]setting, 0, len(out.points)*len(s.points))
+		for _, a := range out.points {
+			for _, b := range s.points {
+				points = append(points, append(append([]setting{}, a...), b...))
+			}
+		}
+		out.points = points
+	}
+	return out
+}
+
+// Zip combines sweeps of the same length point by point
+func Zip(sweeps ...Sweep) Sweep {
+	if len(sweeps) == 0 {
+		return Product()
+	}
+
+	out := Sweep{points: make([][]setting, len(sweeps[0].points))}
+	for _, s := range sweeps {
+		if s.err != nil {
+			return s
+		}
+		if len(s.points) != len(out.points) {
+			return Sweep{err: fmt.Errorf("zipped sweeps differ in length: %d and %d", len(out.points), len(s.points))}
+		}
+
+		for i, p := range s.points {
+			out.points[i] = append(out.points[i], p...)
+		}
+	}
+	return out
+}
+
+// Len returns the number of points
+func (s Sweep) Len() int {
+	return len(s.points)
+}
+
+// Options applies sweep points to base, in order. Numbers are rounded for
+// integer fields.
+func (s Sweep) Options(base BenchOptions) ([]BenchOptions, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	out := make([]BenchOptions, len(s.points))
+	for i, p := range s.points {
+		out[i] = base
+		v := reflect.ValueOf(&out[i]).Elem()
+		for _, set := range p {
+			if err := setField(v, set.field, set.value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// MustOptions is like Options, but panics on errors, for initializing
+// package level sweeps
+func (s Sweep) MustOptions(base BenchOptions) []BenchOptions {
+	out, err := s.Options(base)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+func setField(v reflect.Value, field string, value interface{}) error {
+	f := v.FieldByName(field)
+	if !f.IsValid() {
+		return fmt.Errorf("unknown BenchOptions field '%s'", field)
+	}
+
+	var num float64
+	isNum := true
+	switch n := value.(type) {
+	case int:
+		num = float64(n)
+	case float64:
+		num = n
+	default:
+		isNum = false
+	}
+
+	switch f.Kind() {
+	case reflect.Int:
+		if !isNum {
+			return fmt.Errorf("%s must be a number, got %T(%v)", field, value, value)
+		}
+		f.SetInt(int64(math.Round(num)))
+	case reflect.Float64:
+		if !isNum {
+			return fmt.Errorf("%s must be a number, got %T(%v)", field, value, value)
+		}
+		f.SetFloat(num)
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string, got %T(%v)", field, value, value)
+		}
+		f.SetString(s)
+	default:
+		return fmt.Errorf("can't sweep %s of type %s", field, f.Type())
+	}
+	return nil
+}
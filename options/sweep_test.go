@@ -0,0 +1,117 @@
+package options
+
+import (
+	"reflect"
+	"testing"
+)
+
+func sweepField(t *testing.T, s Sweep, field string) []interface{} {
+	t.Helper()
+
+	opts, err := s.Options(BenchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := make([]interface{}, len(opts))
+	for i, o := range opts {
+		out[i] = reflect.ValueOf(o).FieldByName(field).Interface()
+	}
+	return out
+}
+
+func TestSweepAxes(t *testing.T) {
+	cases := []struct {
+		name   string
+		sweep  Sweep
+		field  string
+		expect []interface{}
+	}{
+		{"values", Values("KeyDistribution", KeyDistUniform, KeyDistZipfian), "KeyDistribution", []interface{}{KeyDistUniform, KeyDistZipfian}},
+		{"linear", Linear("RecordSize", 10, 40, 4), "RecordSize", []interface{}{10, 20, 30, 40}},
+		{"linear rounded", Linear("BatchSize", 1, 10, 3), "BatchSize", []interface{}{1, 6, 10}},
+		{"linear float", Linear("HitRatio", 0, 1, 3), "HitRatio", []interface{}{0.0, 0.5, 1.0}},
+		{"log2", Log2("PrimeRecordCount", 1, 16), "PrimeRecordCount", []interface{}{1, 2, 4, 8, 16}},
+		{"log2 odd start", Log2("PrimeRecordCount", 3, 24), "PrimeRecordCount", []interface{}{3, 6, 12, 24}},
+		{"log2 odd end", Log2("PrimeRecordCount", 1, 10), "PrimeRecordCount", []interface{}{1, 2, 4, 8, 10}},
+		{"log10", Log10("RecordSize", 1, 1000), "RecordSize", []interface{}{1, 10, 100, 1000}},
+		{"geometric", Geometric("TxnSize", 1, 64, 4), "TxnSize", []interface{}{1, 4, 16, 64}},
+		{"log2 fractions", Log2("HitRatio", 0.25, 1), "HitRatio", []interface{}{0.25, 0.5, 1.0}},
+	}
+
+	for _, c := range cases {
+		if got := sweepField(t, c.sweep, c.field); !reflect.DeepEqual(got, c.expect) {
+			t.Errorf("%s: got %v, expected %v", c.name, got, c.expect)
+		}
+	}
+}
+
+func TestSweepCombine(t *testing.T) {
+	base := BenchOptions{PrimeRecordCount: 100}
+
+	opts, err := Product(Values("RecordSize", 1, 2), Values("BatchSize", 10, 20, 30)).Options(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []BenchOptions{
+		{PrimeRecordCount: 100, RecordSize: 1, BatchSize: 10},
+		{PrimeRecordCount: 100, RecordSize: 1, BatchSize: 20},
+		{PrimeRecordCount: 100, RecordSize: 1, BatchSize: 30},
+		{PrimeRecordCount: 100, RecordSize: 2, BatchSize: 10},
+		{PrimeRecordCount: 100, RecordSize: 2, BatchSize: 20},
+		{PrimeRecordCount: 100, RecordSize: 2, BatchSize: 30},
+	}
+	if !reflect.DeepEqual(opts, expect) {
+		t.Errorf("product: got %+v", opts)
+	}
+
+	opts, err = Zip(Values("RecordSize", 1, 2), Values("BatchSize", 10, 20)).Options(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = []BenchOptions{
+		{PrimeRecordCount: 100, RecordSize: 1, BatchSize: 10},
+		{PrimeRecordCount: 100, RecordSize: 2, BatchSize: 20},
+	}
+	if !reflect.DeepEqual(opts, expect) {
+		t.Errorf("zip: got %+v", opts)
+	}
+
+	// zip of products
+	s := Product(Values("KeyFormat", KeyFormatHex, KeyFormatCid), Zip(Values("RecordSize", 1, 2), Values("BatchSize", 10, 20)))
+	if s.Len() != 4 {
+		t.Errorf("expected 4 points, got %d", s.Len())
+	}
+
+	if opts := Product().MustOptions(base); !reflect.DeepEqual(opts, []BenchOptions{base}) {
+		t.Errorf("empty product: got %+v", opts)
+	}
+}
+
+func TestSweepErrors(t *testing.T) {
+	for name, s := range map[string]Sweep{
+		"unknown field":  Values("Nope", 1),
+		"string to int":  Values("RecordSize", "big"),
+		"number to str":  Values("KeyFormat", 1),
+		"zip lengths":    Zip(Values("RecordSize", 1, 2), Values("BatchSize", 1)),
+		"log zero start": Log2("RecordSize", 0, 16),
+		"linear count":   Linear("RecordSize", 1, 2, 0),
+		"nested error":   Product(Values("RecordSize", 1), Log10("BatchSize", 10, 1)),
+	} {
+		if _, err := s.Options(BenchOptions{}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestOptionsRange2powStart(t *testing.T) {
+	opts := OptionsRange2pow(BenchOptions{RecordSize: 1 << 10}, BenchOptions{RecordSize: 1 << 18}, 9)
+	if len(opts) != 9 || opts[0].RecordSize != 1<<10 || opts[8].RecordSize != 1<<18 {
+		t.Fatalf("unexpected points: %+v", opts)
+	}
+	for i, o := range opts {
+		if o.RecordSize != 1<<(10+i) {
+			t.Errorf("point %d: RecordSize %d", i, o.RecordSize)
+		}
+	}
+}
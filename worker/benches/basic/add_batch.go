@@ -15,9 +15,18 @@ func BenchAddBatch(b *testing.B, store ds.Batching, opt options.BenchOptions) {
 	var bufs [][]byte
 	ctx := context.Background()
 
+	ks, err := helpers.NewKeySpace(opt)
+	if err != nil {
+		b.Fatal(err)
+	}
+	vg, err := helpers.NewValueGen(opt)
+	if err != nil {
+		b.Fatal(err)
+	}
+
 	for len(keys) < b.N {
-		bufs = append(bufs, helpers.RandomBuf(opt.RecordSize))
-		keys = append(keys, ds.RandomKey())
+		bufs = append(bufs, vg.Value(opt.RecordSize))
+		keys = append(keys, ks.Key(opt.PrimeRecordCount+len(keys)))
 	}
 
 	b.SetBytes(int64(opt.RecordSize))
@@ -7,41 +7,93 @@ import (
 
 	"github.com/ipfs/go-ds-bench/options"
 	"github.com/ipfs/go-ds-bench/worker/helpers"
-	"github.com/remeh/sizedwaitgroup"
 
 	ds "github.com/ipfs/go-datastore"
 )
 
 func BenchGet(b *testing.B, store ds.Batching, opt options.BenchOptions) {
 	ctx := context.Background()
+	keys, present := lookupKeys(b, store, opt, 1)
 
-	n := b.N
-	if n > opt.PrimeRecordCount/5 {
-		n = opt.PrimeRecordCount / 5
+	vg, err := helpers.NewValueGen(opt)
+	if err != nil {
+		b.Fatal(err)
 	}
+	var mismatches int
 
-	buf := make([]byte, opt.RecordSize)
-	keys := make([]ds.Key, n)
-	swg := sizedwaitgroup.New(256)
+	b.ResetTimer()
 
-	for i := 0; i < n; i++ {
-		buf = helpers.RandomBuf(opt.RecordSize)
-		keys[i] = ds.RandomKey()
+	for i := 0; i < b.N; i++ {
+		v, err := store.Get(ctx, keys[i])
+		if err != nil && err != ds.ErrNotFound {
+			b.Fatal(err)
+		}
+		// verifying counts lost records as mismatches
+		if err == ds.ErrNotFound && present[i] && opt.Verify == "" {
+			b.Fatalf("present key %s not found", keys[i])
+		}
 
-		swg.Add()
-		go func(i int) {
-			defer swg.Done()
-			store.Put(ctx, keys[i], buf)
-		}(i)
+		// regenerating the value costs about as much as reading it, keep it
+		// out of timed reads
+		if opt.Verify == options.VerifyOnRead {
+			b.StopTimer()
+			if !checkValue(vg, keys[i], v, err, present[i], opt.RecordSize) {
+				mismatches++
+			}
+			b.StartTimer()
+		}
 	}
-	swg.Wait()
 
-	b.ResetTimer()
+	b.StopTimer()
+	if opt.Verify == options.VerifyAfter {
+		mismatches = verifyAll(b, store, opt, vg)
+	}
+	reportMismatches(b, opt, mismatches)
+}
 
-	for i := 0; i < b.N; i++ {
-		_, err := store.Get(ctx, keys[i%n])
-		if err != nil {
+// checkValue tells if a value read from the store, with its error, is what
+// was written under key
+func checkValue(vg *helpers.ValueGen, key ds.Key, v []byte, err error, present bool, size int) bool {
+	if err == ds.ErrNotFound {
+		return !present
+	}
+	return present && vg.Check(key, v, size)
+}
+
+// verifyAll reads back primed records and ones written by lookupKeys,
+// returning the number of mismatches
+func verifyAll(b *testing.B, store ds.Batching, opt options.BenchOptions, vg *helpers.ValueGen) int {
+	ctx := context.Background()
+
+	ks, err := helpers.NewKeySpace(opt)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	n := opt.PrimeRecordCount + lookupRecords(b, opt)
+	var mismatches int
+	for i := 0; i < n; i++ {
+		k := ks.Key(i)
+		v, err := store.Get(ctx, k)
+		if err != nil && err != ds.ErrNotFound {
 			b.Fatal(err)
 		}
+		if !checkValue(vg, k, v, err, true, opt.RecordSize) {
+			mismatches++
+		}
+	}
+	return mismatches
+}
+
+// reportMismatches reports the integrity-errors configuration line when
+// verifying, failing the benchmark on mismatches
+func reportMismatches(b *testing.B, opt options.BenchOptions, mismatches int) {
+	if opt.Verify == "" {
+		return
+	}
+
+	helpers.ReportConfig("integrity-errors", mismatches)
+	if mismatches != 0 {
+		b.Errorf("%d values read didn't match written ones", mismatches)
 	}
 }
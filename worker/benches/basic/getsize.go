@@ -0,0 +1,27 @@
+package basic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-ds-bench/options"
+
+	ds "github.com/ipfs/go-datastore"
+)
+
+func BenchGetSize(b *testing.B, store ds.Batching, opt options.BenchOptions) {
+	ctx := context.Background()
+	keys, present := lookupKeys(b, store, opt, 1)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := store.GetSize(ctx, keys[i])
+		if err != nil && err != ds.ErrNotFound {
+			b.Fatal(err)
+		}
+		if err == ds.ErrNotFound && present[i] {
+			b.Fatalf("present key %s not found", keys[i])
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package basic
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/ipfs/go-ds-bench/options"
+	"github.com/ipfs/go-ds-bench/worker/helpers"
+	"github.com/remeh/sizedwaitgroup"
+
+	ds "github.com/ipfs/go-datastore"
+)
+
+// lookupKeys puts a set of records into the store and returns b.N keys to look
+// up, HitRatio of which (defHitRatio if not set) are present in the store, and
+// which of them are
+func lookupKeys(b *testing.B, store ds.Batching, opt options.BenchOptions, defHitRatio float64) ([]ds.Key, []bool) {
+	ctx := context.Background()
+
+	hitRatio := opt.HitRatio
+	switch {
+	case hitRatio == 0:
+		hitRatio = defHitRatio
+	case hitRatio == options.NoHits:
+		hitRatio = 0
+	case hitRatio < 0 || hitRatio > 1:
+		b.Fatalf("hit ratio must be in (0, 1], got %f", hitRatio)
+	}
+
+	n := lookupRecords(b, opt)
+
+	ks, err := helpers.NewKeySpace(opt)
+	if err != nil {
+		b.Fatal(err)
+	}
+	vg, err := helpers.NewValueGen(opt)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	keys := make([]ds.Key, n)
+	swg := sizedwaitgroup.New(256)
+	// failed puts would be looked up as misses, skewing the hit ratio
+	var errOnce sync.Once
+	var firstErr error
+
+	for i := 0; i < n; i++ {
+		keys[i] = ks.Key(opt.PrimeRecordCount + i)
+		buf := vg.Value(opt.RecordSize)
+		if opt.Verify != "" {
+			buf = vg.KeyedValue(keys[i], opt.RecordSize)
+		}
+
+		swg.Add()
+		go func(i int, buf []byte) {
+			defer swg.Done()
+			if err := store.Put(ctx, keys[i], buf); err != nil {
+				errOnce.Do(func() { firstErr = err })
+			}
+		}(i, buf)
+	}
+	swg.Wait()
+	if firstErr != nil {
+		b.Fatal(firstErr)
+	}
+
+	hits, err := helpers.NewKeyDist(opt, n)
+	if err != nil {
+		b.Fatal(err)
+	}
+	misses, err := helpers.NewKeyDist(opt, n)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(int64(n)))
+	out := make([]ds.Key, b.N)
+	present := make([]bool, b.N)
+	for i := range out {
+		if rng.Float64() < hitRatio {
+			out[i] = keys[hits.Next()]
+			present[i] = true
+		} else {
+			// keys after the inserted ones are never written
+			out[i] = ks.Key(opt.PrimeRecordCount + n + misses.Next())
+		}
+	}
+
+	return out, present
+}
+
+// lookupRecords returns the number of records lookupKeys writes, at least
+// one so there are keys to hit
+func lookupRecords(b *testing.B, opt options.BenchOptions) int {
+	n := b.N
+	if n > opt.PrimeRecordCount/5 {
+		n = opt.PrimeRecordCount / 5
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
@@ -0,0 +1,91 @@
+package basic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-ds-bench/options"
+	"github.com/ipfs/go-ds-bench/worker/helpers"
+
+	ds "github.com/ipfs/go-datastore"
+)
+
+// updateSet prepares b.N overwrites of primed records, picked with the
+// configured key distribution
+func updateSet(b *testing.B, opt options.BenchOptions) ([]ds.Key, [][]byte, int) {
+	size := opt.UpdateRecordSize
+	if size == 0 {
+		size = opt.RecordSize
+	}
+
+	ks, err := helpers.NewKeySpace(opt)
+	if err != nil {
+		b.Fatal(err)
+	}
+	vg, err := helpers.NewValueGen(opt)
+	if err != nil {
+		b.Fatal(err)
+	}
+	dist, err := helpers.NewKeyDist(opt, opt.PrimeRecordCount)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	keys := make([]ds.Key, b.N)
+	bufs := make([][]byte, b.N)
+	for i := range keys {
+		keys[i] = ks.Key(dist.Next())
+		bufs[i] = vg.Value(size)
+	}
+
+	return keys, bufs, size
+}
+
+func BenchUpdate(b *testing.B, store ds.Batching, opt options.BenchOptions) {
+	ctx := context.Background()
+	keys, bufs, size := updateSet(b, opt)
+
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err := store.Put(ctx, keys[i], bufs[i])
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchUpdateBatch(b *testing.B, store ds.Batching, opt options.BenchOptions) {
+	ctx := context.Background()
+	keys, bufs, size := updateSet(b, opt)
+
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+
+	batch, err := store.Batch(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		err := batch.Put(ctx, keys[i], bufs[i])
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if i%opt.BatchSize == opt.BatchSize-1 {
+			err = batch.Commit(ctx)
+			if err != nil {
+				b.Fatal(err)
+			}
+			batch, err = store.Batch(ctx)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	err = batch.Commit(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+}
@@ -0,0 +1,156 @@
+// Package txn benchmarks datastores implementing ds.TxnDatastore
+package txn
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ipfs/go-ds-bench/options"
+	"github.com/ipfs/go-ds-bench/worker/helpers"
+
+	ds "github.com/ipfs/go-datastore"
+)
+
+// maxRetries limits commit attempts of a conflicting transaction
+const maxRetries = 1000
+
+func txnSize(opt options.BenchOptions) int {
+	if opt.TxnSize == 0 {
+		return 1
+	}
+	return opt.TxnSize
+}
+
+func concurrency(opt options.BenchOptions) int {
+	if opt.Concurrency == 0 {
+		return 1
+	}
+	return opt.Concurrency
+}
+
+// runTxns runs b.N transactions from concurrency(opt) goroutines, retrying
+// them on conflicts, and reports the conflict rate
+func runTxns(b *testing.B, opt options.BenchOptions, txn func(ctx context.Context, i int) error) {
+	ctx := context.Background()
+	workers := concurrency(opt)
+
+	var conflicts int64
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+
+	b.ResetTimer()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < b.N; i += workers {
+				err := txn(ctx, i)
+				for try := 1; helpers.IsConflict(err) && try < maxRetries; try++ {
+					atomic.AddInt64(&conflicts, 1)
+					err = txn(ctx, i)
+				}
+				if err != nil {
+					errOnce.Do(func() { firstErr = err })
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	b.StopTimer()
+
+	if firstErr != nil {
+		b.Fatal(firstErr)
+	}
+
+	rate := float64(conflicts) / float64(b.N)
+	b.ReportMetric(rate, "conflicts/op")
+	helpers.ReportConfig("conflicts-per-op", rate)
+}
+
+// BenchReadModifyWrite reads TxnSize primed records picked with the configured
+// key distribution, and writes them back changed, in one transaction
+func BenchReadModifyWrite(b *testing.B, store ds.TxnDatastore, opt options.BenchOptions) {
+	size := txnSize(opt)
+
+	ks, err := helpers.NewKeySpace(opt)
+	if err != nil {
+		b.Fatal(err)
+	}
+	dist, err := helpers.NewKeyDist(opt, opt.PrimeRecordCount)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	keys := make([]ds.Key, b.N*size)
+	for i := range keys {
+		keys[i] = ks.Key(dist.Next())
+	}
+
+	b.SetBytes(int64(opt.RecordSize * size))
+
+	runTxns(b, opt, func(ctx context.Context, i int) error {
+		t, err := store.NewTransaction(ctx, false)
+		if err != nil {
+			return err
+		}
+		defer t.Discard(ctx)
+
+		for _, k := range keys[i*size : (i+1)*size] {
+			v, err := t.Get(ctx, k)
+			if err != nil {
+				return err
+			}
+
+			v = append([]byte(nil), v...)
+			if len(v) > 0 {
+				v[0]++
+			}
+			if err := t.Put(ctx, k, v); err != nil {
+				return err
+			}
+		}
+		return t.Commit(ctx)
+	})
+}
+
+// BenchPut writes TxnSize new records in one transaction
+func BenchPut(b *testing.B, store ds.TxnDatastore, opt options.BenchOptions) {
+	size := txnSize(opt)
+
+	ks, err := helpers.NewKeySpace(opt)
+	if err != nil {
+		b.Fatal(err)
+	}
+	vg, err := helpers.NewValueGen(opt)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	keys := make([]ds.Key, b.N*size)
+	bufs := make([][]byte, b.N*size)
+	for i := range keys {
+		keys[i] = ks.Key(opt.PrimeRecordCount + i)
+		bufs[i] = vg.Value(opt.RecordSize)
+	}
+
+	b.SetBytes(int64(opt.RecordSize * size))
+
+	runTxns(b, opt, func(ctx context.Context, i int) error {
+		t, err := store.NewTransaction(ctx, false)
+		if err != nil {
+			return err
+		}
+		defer t.Discard(ctx)
+
+		for j := i * size; j < (i+1)*size; j++ {
+			if err := t.Put(ctx, keys[j], bufs[j]); err != nil {
+				return err
+			}
+		}
+		return t.Commit(ctx)
+	})
+}
@@ -0,0 +1,168 @@
+// Package boltds is a minimal go-datastore backed by a single bbolt bucket.
+package boltds
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucket = []byte("datastore")
+
+type Datastore struct {
+	db *bolt.DB
+}
+
+var _ ds.Batching = (*Datastore)(nil)
+
+// NewDatastore opens (creating if needed) a bolt database in dir. With noSync,
+// bolt doesn't fsync on transaction commit.
+func NewDatastore(dir string, noSync bool, opts *bolt.Options) (*Datastore, error) {
+	db, err := bolt.Open(filepath.Join(dir, "bolt.db"), 0644, opts)
+	if err != nil {
+		return nil, err
+	}
+	db.NoSync = noSync
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Datastore{db: db}, nil
+}
+
+func (d *Datastore) Put(ctx context.Context, key ds.Key, value []byte) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put(key.Bytes(), value)
+	})
+}
+
+func (d *Datastore) Delete(ctx context.Context, key ds.Key) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete(key.Bytes())
+	})
+}
+
+func (d *Datastore) Get(ctx context.Context, key ds.Key) (value []byte, err error) {
+	err = d.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucket).Get(key.Bytes())
+		if v == nil {
+			return ds.ErrNotFound
+		}
+		// bolt values are only valid for the life of the transaction
+		value = append([]byte(nil), v...)
+		return nil
+	})
+	return value, err
+}
+
+func (d *Datastore) Has(ctx context.Context, key ds.Key) (exists bool, err error) {
+	err = d.db.View(func(tx *bolt.Tx) error {
+		exists = tx.Bucket(bucket).Get(key.Bytes()) != nil
+		return nil
+	})
+	return exists, err
+}
+
+func (d *Datastore) GetSize(ctx context.Context, key ds.Key) (size int, err error) {
+	err = d.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucket).Get(key.Bytes())
+		if v == nil {
+			return ds.ErrNotFound
+		}
+		size = len(v)
+		return nil
+	})
+	return size, err
+}
+
+func (d *Datastore) Query(ctx context.Context, q dsq.Query) (dsq.Results, error) {
+	prefix := ds.NewKey(q.Prefix).String()
+	if prefix != "/" {
+		prefix += "/"
+	}
+
+	var entries []dsq.Entry
+	err := d.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		for k, v := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = c.Next() {
+			e := dsq.Entry{Key: string(k), Size: len(v)}
+			if !q.KeysOnly {
+				e.Value = append([]byte(nil), v...)
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// prefix is already applied
+	q.Prefix = ""
+	return dsq.NaiveQueryApply(q, dsq.ResultsWithEntries(q, entries)), nil
+}
+
+func (d *Datastore) Sync(ctx context.Context, prefix ds.Key) error {
+	return d.db.Sync()
+}
+
+func (d *Datastore) Close() error {
+	return d.db.Close()
+}
+
+func (d *Datastore) Batch(ctx context.Context) (ds.Batch, error) {
+	return &batch{
+		db:  d.db,
+		ops: map[ds.Key][]byte{},
+	}, nil
+}
+
+// batch buffers operations and applies them in a single transaction, nil
+// value marks a delete
+type batch struct {
+	db  *bolt.DB
+	ops map[ds.Key][]byte
+}
+
+func (b *batch) Put(ctx context.Context, key ds.Key, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	b.ops[key] = value
+	return nil
+}
+
+func (b *batch) Delete(ctx context.Context, key ds.Key) error {
+	b.ops[key] = nil
+	return nil
+}
+
+func (b *batch) Commit(ctx context.Context) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(bucket)
+		for k, v := range b.ops {
+			var err error
+			if v == nil {
+				err = bkt.Delete(k.Bytes())
+			} else {
+				err = bkt.Put(k.Bytes(), v)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	b.ops = map[ds.Key][]byte{}
+	return err
+}
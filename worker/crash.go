@@ -0,0 +1,234 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-ds-bench/options"
+	"github.com/ipfs/go-ds-bench/worker/helpers"
+)
+
+// crashMaxWrites bounds the number of records a crash child writes before
+// getting killed, children acknowledge whole batches
+const crashMaxWrites = 4096
+
+// RunCrashBench checks writes acknowledged before a crash survive it. Every
+// round a child process (see Crash) writes records with the measured sync
+// mode, acknowledging them as they return, and gets killed after a random
+// number of acknowledgements, up to crashMaxWrites records' worth. The datastore is then reopened and acknowledged
+// records are read back, missing ones are counted as lost, ones with wrong
+// contents as corrupted.
+//
+// Kill points are drawn from a source seeded with the options descriptor, so
+// runs are repeatable and all candidates are killed after the same number of
+// acknowledgements. ns/op is the time per acknowledged write, from the child
+// having the datastore open to its last acknowledgement, without spawning
+// the child, opening the datastore and checking records.
+//
+// Killing a process doesn't drop the OS page cache, so only writes buffered by
+// the datastore itself can be lost, losses on power failure aren't simulated.
+// Like unclean reopen, it needs candidates taking the ReuseDir param.
+func RunCrashBench(b *testing.B, spec options.WorkerDatastore, opt options.BenchOptions) {
+	spec.Params = reuseDir(spec.Params)
+	store := CandidateDs(spec)
+	printSyncMode(store)
+
+	b.Run(opt.TestDesc(), func(b *testing.B) {
+		if !hasParam(spec.Type, "ReuseDir") {
+			helpers.ReportConfig("unsupported", "crash")
+			b.Skipf("%s can't be opened from other processes", spec.Type)
+		}
+
+		ks, err := helpers.NewKeySpace(opt)
+		if err != nil {
+			b.Fatal(err)
+		}
+		vg, err := helpers.NewValueGen(opt)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		newStore, err := store.Create()
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer store.Destroy()
+
+		s, closer, err := newStore(true)
+		if err != nil {
+			b.Fatal(err)
+		}
+		PrimeDS(b, s, opt)
+		closer.Close()
+		syscall.Sync()
+
+		ctx := context.Background()
+		seed := fnv.New64a()
+		seed.Write([]byte(opt.Descriptor()))
+		rng := rand.New(rand.NewSource(int64(seed.Sum64())))
+		next := opt.PrimeRecordCount
+		var acked, lost, corrupted int
+		var writing time.Duration
+		maxAcks := crashMaxWrites
+		if opt.BatchSize > 1 {
+			maxAcks = crashMaxWrites / opt.BatchSize
+		}
+		if maxAcks < 1 {
+			maxAcks = 1
+		}
+
+		b.ResetTimer()
+		b.StopTimer()
+		for i := 0; i < b.N; i++ {
+			last, took, err := runCrash(next, 1+rng.Intn(maxAcks))
+			if err != nil {
+				b.Fatal(err)
+			}
+			writing += took
+
+			s, closer, err := newStore(true)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for n := next; n <= last; n++ {
+				k := ks.Key(n)
+				v, err := s.Get(ctx, k)
+				switch {
+				case err == ds.ErrNotFound:
+					lost++
+				case err != nil:
+					b.Fatal(err)
+				case !vg.Check(k, v, opt.RecordSize):
+					corrupted++
+				}
+			}
+			if err := closer.Close(); err != nil {
+				b.Fatal(err)
+			}
+
+			acked += last - next + 1
+			next = last + 1
+		}
+
+		b.ReportMetric(float64(writing.Nanoseconds())/float64(acked), "ns/op")
+		b.ReportMetric(float64(lost), "lost")
+		b.ReportMetric(float64(corrupted), "corrupted")
+		helpers.ReportConfig("acked-writes", acked)
+		helpers.ReportConfig("lost-writes", lost)
+		helpers.ReportConfig("corrupted-writes", corrupted)
+	})
+
+	helpers.FlushConfig(os.Stdout)
+}
+
+// runCrash runs Crash in a child process writing from record start, kills it
+// after acks acknowledgements, and returns the last acknowledged record, with
+// the time from the child being ready to write to that acknowledgement
+func runCrash(start, acks int) (int, time.Duration, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	cmd := exec.Command(exe, "-crash", strconv.Itoa(start))
+	cmd.Stderr = os.Stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, 0, err
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	sc := bufio.NewScanner(out)
+	if !sc.Scan() || sc.Text() != crashReady {
+		return 0, 0, fmt.Errorf("crash child didn't get ready: %q %v", sc.Text(), sc.Err())
+	}
+	began := time.Now()
+
+	last := start - 1
+	for n := 0; n < acks && sc.Scan(); n++ {
+		if last, err = strconv.Atoi(sc.Text()); err != nil {
+			return 0, 0, fmt.Errorf("crash child: %q", sc.Text())
+		}
+	}
+	took := time.Since(began)
+	if err := sc.Err(); err != nil {
+		return 0, 0, err
+	}
+	if last < start {
+		return 0, 0, fmt.Errorf("crash child exited before acknowledging writes")
+	}
+	return last, took, nil
+}
+
+// crashReady is printed by crash children once the datastore is open
+const crashReady = "ready"
+
+// Crash opens the datastore from spec in place, with the measured sync mode,
+// and writes records from start on until killed, printing crashReady once it's
+// open and indexes of records once their write (or the batch commit, with
+// BatchSize > 1) returns
+func Crash(spec options.TestSpec, start int) error {
+	spec.Datastore.Params = reuseDir(spec.Datastore.Params)
+	opt := spec.Options
+
+	ks, err := helpers.NewKeySpace(opt)
+	if err != nil {
+		return err
+	}
+	vg, err := helpers.NewValueGen(opt)
+	if err != nil {
+		return err
+	}
+
+	newStore, err := CandidateDs(spec.Datastore).Create()
+	if err != nil {
+		return err
+	}
+	s, _, err := newStore(false)
+	if err != nil {
+		return err
+	}
+	fmt.Println(crashReady)
+
+	ctx := context.Background()
+	if opt.BatchSize <= 1 {
+		for i := start; ; i++ {
+			k := ks.Key(i)
+			if err := s.Put(ctx, k, vg.KeyedValue(k, opt.RecordSize)); err != nil {
+				return err
+			}
+			fmt.Println(i)
+		}
+	}
+
+	for i := start; ; i += opt.BatchSize {
+		batch, err := s.Batch(ctx)
+		if err != nil {
+			return err
+		}
+		for n := i; n < i+opt.BatchSize; n++ {
+			k := ks.Key(n)
+			if err := batch.Put(ctx, k, vg.KeyedValue(k, opt.RecordSize)); err != nil {
+				return err
+			}
+		}
+		if err := batch.Commit(ctx); err != nil {
+			return err
+		}
+		fmt.Println(i + opt.BatchSize - 1)
+	}
+}
@@ -5,21 +5,20 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	ds "github.com/ipfs/go-datastore"
-	// badgerds "github.com/ipfs/go-ds-badger"
 	"github.com/ipfs/go-ds-bench/options"
+	"github.com/ipfs/go-ds-bench/worker/boltds"
 
-	// boltds "github.com/ipfs/go-ds-bolt"
+	bolt "go.etcd.io/bbolt"
 
 	flatfs "github.com/ipfs/go-ds-flatfs"
 	leveldb "github.com/ipfs/go-ds-leveldb"
+	"github.com/syndtr/goleveldb/leveldb/filter"
 	levelopt "github.com/syndtr/goleveldb/leveldb/opt"
 
-	radosds "github.com/coryschwartz/go-ds-rados"
-
 	"github.com/mitchellh/go-homedir"
-	// "gx/ipfs/QmdcULN1WCzgoQmcCaUAmEhwcxHYsDrbZ2LvRJKCL8dMrK/go-homedir"
 )
 
 func nopCloser() {}
@@ -27,130 +26,223 @@ func nopCloser() {}
 type CandidateDatastore struct {
 	Create  func() (func(fast bool) (ds.Batching, io.Closer, error), error)
 	Destroy func()
+
+	// SyncMode returns the options.Sync* mode datastores are opened with when
+	// priming (fast) or measuring
+	SyncMode func(fast bool) string
+}
+
+func init() {
+	RegisterCandidate("memory-map", CandidateMemoryMap, nil)
+	RegisterCandidate("flatfs", CandidateFlatfs, flatfsParams{ShardFunc: "next-to-last", ShardLength: 2})
+	RegisterCandidate("leveldb", CandidateLeveldb, leveldbParams{})
+	RegisterCandidate("bolt", CandidateBolt, boltParams{})
+}
+
+// diskParams are shared by datastores keeping data in a local directory
+type diskParams struct {
+	DataDir  string `param:"required" doc:"directory to create datastores in, removed after the test"`
+	SyncMode string `doc:"none, per-op or per-batch; applies while measuring, priming never syncs"`
+	Sync     bool   `doc:"deprecated, same as SyncMode per-op"`
+	ReuseDir bool   `doc:"keep data in DataDir itself instead of a new directory in it, so other processes can open the same datastore"`
+}
+
+// syncMode resolves the sync mode to measure with, checking the backend
+// supports it
+func (p diskParams) syncMode(supported ...string) (string, error) {
+	mode := p.SyncMode
+	if p.Sync {
+		if mode != "" && mode != options.SyncPerOp {
+			return "", fmt.Errorf("Sync conflicts with SyncMode '%s'", mode)
+		}
+		mode = options.SyncPerOp
+	}
+	if mode == "" {
+		mode = options.SyncNone
+	}
+
+	for _, s := range supported {
+		if s == mode {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported SyncMode '%s', supported: %s", mode, strings.Join(supported, ", "))
 }
 
-var datastores = map[string]func(options.WorkerDatastore) CandidateDatastore{
-	"memory-map": CandidateMemoryMap,
-	"flatfs":     CandidateFlatfs,
-	// "badger":     CandidateBadger,
-	"leveldb": CandidateLeveldb,
-	// "bolt":       CandidateBolt,
-	"rados": CandidateRados,
+// phaseSync returns CandidateDatastore.SyncMode for datastores which don't
+// sync while priming
+func phaseSync(mode string) func(fast bool) string {
+	return func(fast bool) string {
+		if fast {
+			return options.SyncNone
+		}
+		return mode
+	}
 }
 
-var CandidateMemoryMap = func(options.WorkerDatastore) CandidateDatastore {
+// mkdir creates a fresh directory for the datastore
+func (p diskParams) mkdir() (string, error) {
+	d, err := homedir.Expand(p.DataDir)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(d, 0775)
+	if err != nil {
+		return "", err
+	}
+	if p.ReuseDir {
+		return d, nil
+	}
+
+	return ioutil.TempDir(d, "bench")
+}
+
+func (p diskParams) destroy() {
+	if p.DataDir == "" {
+		return
+	}
+
+	d, err := homedir.Expand(p.DataDir)
+	if err != nil {
+		return
+	}
+
+	os.RemoveAll(d)
+}
+
+var CandidateMemoryMap = func(spec options.WorkerDatastore) CandidateDatastore {
+	perr := options.DecodeParams(spec.Params, &struct{}{})
+
 	return CandidateDatastore{
 		Create: func() (func(bool) (ds.Batching, io.Closer, error), error) {
+			if perr != nil {
+				return nil, perr
+			}
+
 			mds := ds.NewMapDatastore()
 
 			return func(fast bool) (ds.Batching, io.Closer, error) {
 				return mds, mds, nil
 			}, nil
 		},
-		Destroy: nopCloser,
+		Destroy:  nopCloser,
+		SyncMode: phaseSync(options.SyncNone),
+	}
+}
+
+type flatfsParams struct {
+	diskParams
+
+	ShardFunc   string `doc:"prefix, suffix or next-to-last"`
+	ShardLength int    `doc:"number of key characters naming a shard directory"`
+}
+
+// shard returns the configured flatfs sharding function
+func (p flatfsParams) shard() (*flatfs.ShardIdV1, error) {
+	if p.ShardLength < 1 {
+		return nil, fmt.Errorf("flatfs ShardLength must be positive, got %d", p.ShardLength)
+	}
+
+	switch p.ShardFunc {
+	case "prefix":
+		return flatfs.Prefix(p.ShardLength), nil
+	case "suffix":
+		return flatfs.Suffix(p.ShardLength), nil
+	case "next-to-last":
+		return flatfs.NextToLast(p.ShardLength), nil
+	default:
+		return nil, fmt.Errorf("unknown flatfs ShardFunc: '%s'", p.ShardFunc)
 	}
 }
 
 var CandidateFlatfs = func(spec options.WorkerDatastore) CandidateDatastore {
-	return CandidateDatastore{
-		Create: func() (func(bool) (ds.Batching, io.Closer, error), error) {
-			d, err := homedir.Expand(spec.Params["DataDir"].(string))
-			if err != nil {
-				return nil, err
-			}
+	p := flatfsParams{ShardFunc: "next-to-last", ShardLength: 2}
+	perr := options.DecodeParams(spec.Params, &p)
 
-			err = os.MkdirAll(d, 0775)
-			if err != nil {
-				return nil, err
-			}
+	var shard *flatfs.ShardIdV1
+	if perr == nil {
+		shard, perr = p.shard()
+	}
+	var mode string
+	if perr == nil {
+		mode, perr = p.syncMode(options.SyncNone, options.SyncPerOp)
+	}
+	sync := phaseSync(mode)
 
-			dir, err := ioutil.TempDir(d, "bench")
-			if err != nil {
-				return nil, err
+	return CandidateDatastore{
+		Create: func() (func(bool) (ds.Batching, io.Closer, error), error) {
+			if perr != nil {
+				return nil, perr
 			}
 
-			err = os.MkdirAll(dir, 0775)
+			dir, err := p.mkdir()
 			if err != nil {
 				return nil, err
 			}
 
 			return func(fast bool) (ds.Batching, io.Closer, error) {
-				fs, err := flatfs.CreateOrOpen(dir, flatfs.NextToLast(2), !fast && spec.Params["Sync"].(bool))
+				fs, err := flatfs.CreateOrOpen(dir, shard, flatfsSync(sync(fast)))
 				return fs, fs, err
 			}, nil
 		},
-		Destroy: func() {
-			d, err := homedir.Expand(spec.Params["DataDir"].(string))
-			if err != nil {
-				return
-			}
+		Destroy:  p.destroy,
+		SyncMode: sync,
+	}
+}
 
-			os.RemoveAll(d)
-		},
+// flatfsKeys rejects options putting keys in namespaces flatfs can't store,
+// it only takes top level keys. KeyPrefix works under a mount wrapper
+// removing the prefix, nested KeyDepth keys never do.
+func flatfsKeys(spec options.WorkerDatastore, opt options.BenchOptions) error {
+	if spec.Type != "flatfs" {
+		return nil
+	}
+	if opt.KeyDepth > 0 {
+		return fmt.Errorf("flatfs only stores top level keys, KeyDepth %d can't be used with it", opt.KeyDepth)
 	}
+	if opt.KeyPrefix == "" {
+		return nil
+	}
+	for _, w := range spec.Wrappers {
+		if w.Type == "mount" {
+			return nil
+		}
+	}
+	return fmt.Errorf("flatfs only stores top level keys, KeyPrefix '%s' needs a mount wrapper at the prefix", opt.KeyPrefix)
+}
+
+// flatfsSync tells if flatfs syncs in mode, it syncs puts and batches alike
+func flatfsSync(mode string) bool {
+	return mode == options.SyncPerOp
 }
 
-// var CandidateBadger = func(spec options.WorkerDatastore) CandidateDatastore {
-// 	return CandidateDatastore{
-// 		Create: func() (func(bool) (ds.Batching, io.Closer, error), error) {
-// 			d, err := homedir.Expand(spec.Params["DataDir"].(string))
-// 			if err != nil {
-// 				return nil, err
-// 			}
-
-// 			err = os.MkdirAll(d, 0775)
-// 			if err != nil {
-// 				return nil, err
-// 			}
-
-// 			dir, err := ioutil.TempDir(d, "bench")
-// 			if err != nil {
-// 				return nil, err
-// 			}
-
-// 			err = os.MkdirAll(dir, 0775)
-// 			if err != nil {
-// 				return nil, err
-// 			}
-
-// 			return func(fast bool) (ds.Batching, io.Closer, error) {
-// 				opts := badgerds.DefaultOptions
-// 				opts.SyncWrites = !fast && spec.Params["Sync"].(bool)
-// 				d, err := badgerds.NewDatastore(dir, &opts)
-
-// 				return d, d, err
-// 			}, nil
-// 		},
-// 		Destroy: func() {
-// 			d, err := homedir.Expand(spec.Params["DataDir"].(string))
-// 			if err != nil {
-// 				return
-// 			}
-
-// 			os.RemoveAll(d)
-// 		},
-// 	}
-// }
+type leveldbParams struct {
+	diskParams
+
+	BlockCacheCapacity  int `doc:"block cache size in bytes, 0 for leveldb default"`
+	WriteBuffer         int `doc:"memtable size in bytes, 0 for leveldb default"`
+	CompactionTableSize int `doc:"sstable size in bytes, 0 for leveldb default"`
+	BloomFilterBits     int `doc:"bloom filter bits per key, 0 disables the filter"`
+}
 
 var CandidateLeveldb = func(spec options.WorkerDatastore) CandidateDatastore {
-	return CandidateDatastore{
-		Create: func() (func(bool) (ds.Batching, io.Closer, error), error) {
-			d, err := homedir.Expand(spec.Params["DataDir"].(string))
-			if err != nil {
-				return nil, err
-			}
+	p := leveldbParams{}
+	perr := options.DecodeParams(spec.Params, &p)
 
-			err = os.MkdirAll(d, 0775)
-			if err != nil {
-				return nil, err
-			}
+	var mode string
+	if perr == nil {
+		mode, perr = p.syncMode(options.SyncNone, options.SyncPerOp)
+	}
+	sync := phaseSync(mode)
 
-			dir, err := ioutil.TempDir(d, "bench")
-			if err != nil {
-				return nil, err
+	return CandidateDatastore{
+		Create: func() (func(bool) (ds.Batching, io.Closer, error), error) {
+			if perr != nil {
+				return nil, perr
 			}
 
-			err = os.MkdirAll(dir, 0775)
+			dir, err := p.mkdir()
 			if err != nil {
 				return nil, err
 			}
@@ -158,112 +250,140 @@ var CandidateLeveldb = func(spec options.WorkerDatastore) CandidateDatastore {
 			return func(fast bool) (ds.Batching, io.Closer, error) {
 				opts := leveldb.Options{
 					Compression: levelopt.DefaultCompression,
-					NoSync:      !fast && !spec.Params["Sync"].(bool),
+					NoSync:      leveldbNoSync(sync(fast)),
+
+					BlockCacheCapacity:  p.BlockCacheCapacity,
+					WriteBuffer:         p.WriteBuffer,
+					CompactionTableSize: p.CompactionTableSize,
+				}
+				if p.BloomFilterBits > 0 {
+					opts.Filter = filter.NewBloomFilter(p.BloomFilterBits)
 				}
 
 				ldb, err := leveldb.NewDatastore(dir, &opts)
 				return ldb, ldb, err
 			}, nil
 		},
-		Destroy: func() {
-			d, err := homedir.Expand(spec.Params["DataDir"].(string))
-			if err != nil {
-				return
-			}
-
-			os.RemoveAll(d)
-		},
+		Destroy:  p.destroy,
+		SyncMode: sync,
 	}
 }
 
-var CandidateRados = func(spec options.WorkerDatastore) CandidateDatastore {
+// leveldbNoSync tells if leveldb skips syncing in mode, it syncs puts and
+// batches alike
+func leveldbNoSync(mode string) bool {
+	return mode != options.SyncPerOp
+}
+
+type boltParams struct {
+	diskParams
+
+	NoFreelistSync  bool
+	InitialMmapSize int
+	FreelistType    string `doc:"array or hashmap"`
+}
+
+var CandidateBolt = func(spec options.WorkerDatastore) CandidateDatastore {
+	p := boltParams{}
+	perr := options.DecodeParams(spec.Params, &p)
+	if perr == nil && p.FreelistType != "" && p.FreelistType != string(bolt.FreelistArrayType) && p.FreelistType != string(bolt.FreelistMapType) {
+		perr = fmt.Errorf("unknown bolt FreelistType: '%s'", p.FreelistType)
+	}
+
+	var mode string
+	if perr == nil {
+		mode, perr = p.syncMode(options.SyncNone, options.SyncPerOp, options.SyncPerBatch)
+	}
+	sync := phaseSync(mode)
+
 	return CandidateDatastore{
 		Create: func() (func(bool) (ds.Batching, io.Closer, error), error) {
-			cfg, ok := spec.Params["CephConfig"].(string)
-			if !ok {
-				return nil, fmt.Errorf("CephConfig is required")
+			if perr != nil {
+				return nil, perr
 			}
-			pool, ok := spec.Params["CephPool"].(string)
-			if !ok {
-				return nil, fmt.Errorf("CephPool is required")
+
+			dir, err := p.mkdir()
+			if err != nil {
+				return nil, err
 			}
-			return func(bool) (ds.Batching, io.Closer, error) {
-				ds, err := radosds.NewDatastore(cfg, pool)
+
+			return func(fast bool) (ds.Batching, io.Closer, error) {
+				opts := *bolt.DefaultOptions
+				opts.NoFreelistSync = p.NoFreelistSync
+				opts.InitialMmapSize = p.InitialMmapSize
+				if p.FreelistType != "" {
+					opts.FreelistType = bolt.FreelistType(p.FreelistType)
+				}
+
+				d, err := boltds.NewDatastore(dir, boltNoSync(sync(fast)), &opts)
 				if err != nil {
 					return nil, nil, err
 				}
-				return ds, ds, nil
+				return withSync(d, batchSync(sync(fast))), d, nil
 			}, nil
 		},
-		Destroy: func() {
-			d, err := homedir.Expand(spec.Params["DataDir"].(string))
-			if err != nil {
-				return
-			}
-
-			os.RemoveAll(d)
-		},
+		Destroy:  p.destroy,
+		SyncMode: sync,
 	}
 }
 
-// var CandidateBolt = func(spec options.WorkerDatastore) CandidateDatastore {
-// 	return CandidateDatastore{
-// 		Create: func() (func(bool) (ds.Batching, io.Closer, error), error) {
-// 			d, err := homedir.Expand(spec.Params["DataDir"].(string))
-// 			if err != nil {
-// 				return nil, err
-// 			}
-
-// 			err = os.MkdirAll(d, 0775)
-// 			if err != nil {
-// 				return nil, err
-// 			}
-
-// 			dir, err := ioutil.TempDir(d, "bench")
-// 			if err != nil {
-// 				return nil, err
-// 			}
-
-// 			err = os.MkdirAll(dir, 0775)
-// 			if err != nil {
-// 				return nil, err
-// 			}
-
-// 			return func(fast bool) (ds.Batching, io.Closer, error) {
-// 				d, err := boltds.NewBoltDatastore(dir, "test", fast || !spec.Params["Sync"].(bool))
-// 				return d, d, err
-// 			}, nil
-// 		},
-// 		Destroy: func() {
-// 			d, err := homedir.Expand(spec.Params["DataDir"].(string))
-// 			if err != nil {
-// 				return
-// 			}
-
-// 			os.RemoveAll(d)
-// 		},
-// 	}
-// }
+// boltNoSync tells if bolt skips syncing transaction commits in mode, batches
+// are synced by withSync in per-batch mode
+func boltNoSync(mode string) bool {
+	return mode != options.SyncPerOp
+}
+
+// setNonZero sets *dst to v, unless v is zero
+func setNonZero[T comparable](dst *T, v T) {
+	var zero T
+	if v != zero {
+		*dst = v
+	}
+}
 
 var CandidateDs = func(spec options.WorkerDatastore) CandidateDatastore {
 	return CandidateDatastore{
 		Create: func() (func(bool) (ds.Batching, io.Closer, error), error) {
-			d, ok := datastores[spec.Type]
+			d, ok := candidate(spec.Type)
 			if !ok {
-				return nil, fmt.Errorf("unknows ds: '%s'", spec.Type)
+				return nil, fmt.Errorf("unknown ds: '%s', available: %s", spec.Type, strings.Join(Candidates(), ", "))
+			}
+
+			wrap, err := wrapChain(spec.Wrappers)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", spec.Name, err)
 			}
 
 			construct, err := d(spec).Create()
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("%s: %w", spec.Name, err)
 			}
 
 			return func(fast bool) (ds.Batching, io.Closer, error) {
-				return construct(fast)
+				d, c, err := construct(fast)
+				if err != nil || len(spec.Wrappers) == 0 {
+					return d, c, err
+				}
+
+				// wrappers close the datastores they wrap
+				w, err := wrap(d)
+				if err != nil {
+					c.Close()
+					return nil, nil, fmt.Errorf("%s: %w", spec.Name, err)
+				}
+				return w, w, nil
 			}, nil
 		},
 		Destroy: func() {
-			datastores[spec.Type](spec).Destroy()
+			if d, ok := candidate(spec.Type); ok {
+				d(spec).Destroy()
+			}
+		},
+		SyncMode: func(fast bool) string {
+			if d, ok := candidate(spec.Type); ok {
+				return d(spec).SyncMode(fast)
+			}
+			return ""
 		},
 	}
 }
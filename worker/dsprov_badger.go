@@ -0,0 +1,139 @@
+//go:build !nobadger
+// +build !nobadger
+
+package worker
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dgraph-io/badger"
+	badger2 "github.com/dgraph-io/badger/v2"
+	badger4 "github.com/dgraph-io/badger/v4"
+	ds "github.com/ipfs/go-datastore"
+	badgerds "github.com/ipfs/go-ds-badger"
+	badger2ds "github.com/ipfs/go-ds-badger2"
+	badger4ds "github.com/ipfs/go-ds-badger4"
+	"github.com/ipfs/go-ds-bench/options"
+	"github.com/ipfs/go-ds-bench/worker/helpers"
+)
+
+func init() {
+	RegisterCandidate("badger", CandidateBadger, badgerDefaults)
+	helpers.RegisterConflictErr(badger.ErrConflict, badger2.ErrConflict, badger4.ErrConflict)
+}
+
+type badgerParams struct {
+	diskParams
+
+	Version int `doc:"go-ds-badger major version: 1, 2 or 4"`
+
+	// zero keeps badger defaults
+	ValueThreshold          int
+	NumMemtables            int
+	NumLevelZeroTables      int
+	NumLevelZeroTablesStall int
+	NumCompactors           int
+	ValueLogFileSize        int
+	TableSize               int `doc:"MaxTableSize, BaseTableSize in v4"`
+	BlockCacheSize          int `doc:"v2 and v4 only"`
+}
+
+var badgerDefaults = badgerParams{Version: 1}
+
+var CandidateBadger = func(spec options.WorkerDatastore) CandidateDatastore {
+	p := badgerDefaults
+	perr := options.DecodeParams(spec.Params, &p)
+	if perr == nil && p.Version != 1 && p.Version != 2 && p.Version != 4 {
+		perr = fmt.Errorf("unsupported badger version: %d", p.Version)
+	}
+	if perr == nil && p.Version == 1 && p.BlockCacheSize != 0 {
+		perr = fmt.Errorf("BlockCacheSize isn't supported by badger v1")
+	}
+
+	var mode string
+	if perr == nil {
+		mode, perr = p.syncMode(options.SyncNone, options.SyncPerOp, options.SyncPerBatch)
+	}
+	sync := phaseSync(mode)
+
+	return CandidateDatastore{
+		Create: func() (func(bool) (ds.Batching, io.Closer, error), error) {
+			if perr != nil {
+				return nil, perr
+			}
+
+			dir, err := p.mkdir()
+			if err != nil {
+				return nil, err
+			}
+
+			switch p.Version {
+			case 1:
+				return func(fast bool) (ds.Batching, io.Closer, error) {
+					opts := badgerds.DefaultOptions
+					opts.SyncWrites = badgerSyncWrites(sync(fast))
+					setNonZero(&opts.ValueThreshold, p.ValueThreshold)
+					setNonZero(&opts.NumMemtables, p.NumMemtables)
+					setNonZero(&opts.NumLevelZeroTables, p.NumLevelZeroTables)
+					setNonZero(&opts.NumLevelZeroTablesStall, p.NumLevelZeroTablesStall)
+					setNonZero(&opts.NumCompactors, p.NumCompactors)
+					setNonZero(&opts.ValueLogFileSize, int64(p.ValueLogFileSize))
+					setNonZero(&opts.MaxTableSize, int64(p.TableSize))
+
+					d, err := badgerds.NewDatastore(dir, &opts)
+					if err != nil {
+						return nil, nil, err
+					}
+					return withSync(d, batchSync(sync(fast))), d, nil
+				}, nil
+			case 2:
+				return func(fast bool) (ds.Batching, io.Closer, error) {
+					opts := badger2ds.DefaultOptions
+					opts.SyncWrites = badgerSyncWrites(sync(fast))
+					setNonZero(&opts.ValueThreshold, p.ValueThreshold)
+					setNonZero(&opts.NumMemtables, p.NumMemtables)
+					setNonZero(&opts.NumLevelZeroTables, p.NumLevelZeroTables)
+					setNonZero(&opts.NumLevelZeroTablesStall, p.NumLevelZeroTablesStall)
+					setNonZero(&opts.NumCompactors, p.NumCompactors)
+					setNonZero(&opts.ValueLogFileSize, int64(p.ValueLogFileSize))
+					setNonZero(&opts.MaxTableSize, int64(p.TableSize))
+					setNonZero(&opts.BlockCacheSize, int64(p.BlockCacheSize))
+
+					d, err := badger2ds.NewDatastore(dir, &opts)
+					if err != nil {
+						return nil, nil, err
+					}
+					return withSync(d, batchSync(sync(fast))), d, nil
+				}, nil
+			default:
+				return func(fast bool) (ds.Batching, io.Closer, error) {
+					opts := badger4ds.DefaultOptions
+					opts.SyncWrites = badgerSyncWrites(sync(fast))
+					setNonZero(&opts.ValueThreshold, int64(p.ValueThreshold))
+					setNonZero(&opts.NumMemtables, p.NumMemtables)
+					setNonZero(&opts.NumLevelZeroTables, p.NumLevelZeroTables)
+					setNonZero(&opts.NumLevelZeroTablesStall, p.NumLevelZeroTablesStall)
+					setNonZero(&opts.NumCompactors, p.NumCompactors)
+					setNonZero(&opts.ValueLogFileSize, int64(p.ValueLogFileSize))
+					setNonZero(&opts.BaseTableSize, int64(p.TableSize))
+					setNonZero(&opts.BlockCacheSize, int64(p.BlockCacheSize))
+
+					d, err := badger4ds.NewDatastore(dir, &opts)
+					if err != nil {
+						return nil, nil, err
+					}
+					return withSync(d, batchSync(sync(fast))), d, nil
+				}, nil
+			}
+		},
+		Destroy:  p.destroy,
+		SyncMode: sync,
+	}
+}
+
+// badgerSyncWrites tells if badger syncs transaction commits in mode, batches
+// are synced by withSync in per-batch mode
+func badgerSyncWrites(mode string) bool {
+	return mode == options.SyncPerOp
+}
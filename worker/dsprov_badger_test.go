@@ -0,0 +1,22 @@
+//go:build !nobadger
+// +build !nobadger
+
+package worker
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-ds-bench/options"
+)
+
+func TestBadgerNativeSync(t *testing.T) {
+	for mode, sync := range map[string]bool{
+		options.SyncNone:     false,
+		options.SyncPerOp:    true,
+		options.SyncPerBatch: false,
+	} {
+		if badgerSyncWrites(mode) != sync {
+			t.Errorf("badger %s: SyncWrites=%t", mode, badgerSyncWrites(mode))
+		}
+	}
+}
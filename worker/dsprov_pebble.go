@@ -0,0 +1,89 @@
+//go:build !nopebble
+// +build !nopebble
+
+package worker
+
+import (
+	"fmt"
+	"io"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-ds-bench/options"
+
+	"github.com/cockroachdb/pebble"
+	pebbleds "github.com/ipfs/go-ds-pebble"
+)
+
+func init() {
+	RegisterCandidate("pebble", CandidatePebble, pebbleParams{})
+}
+
+type pebbleParams struct {
+	diskParams
+
+	// zero keeps pebble defaults
+	CacheSize                int
+	MemTableSize             int
+	BytesPerSync             int
+	L0CompactionThreshold    int
+	L0StopWritesThreshold    int
+	MaxConcurrentCompactions int
+	DisableWAL               bool
+}
+
+var CandidatePebble = func(spec options.WorkerDatastore) CandidateDatastore {
+	p := pebbleParams{}
+	perr := options.DecodeParams(spec.Params, &p)
+
+	var mode string
+	if perr == nil {
+		mode, perr = p.syncMode(options.SyncNone, options.SyncPerOp, options.SyncPerBatch)
+	}
+	if perr == nil && p.DisableWAL && mode != options.SyncNone {
+		perr = fmt.Errorf("SyncMode %s needs the WAL", mode)
+	}
+	sync := phaseSync(mode)
+
+	return CandidateDatastore{
+		Create: func() (func(bool) (ds.Batching, io.Closer, error), error) {
+			if perr != nil {
+				return nil, perr
+			}
+
+			dir, err := p.mkdir()
+			if err != nil {
+				return nil, err
+			}
+
+			return func(fast bool) (ds.Batching, io.Closer, error) {
+				opts := &pebble.Options{
+					MemTableSize:          p.MemTableSize,
+					BytesPerSync:          p.BytesPerSync,
+					L0CompactionThreshold: p.L0CompactionThreshold,
+					L0StopWritesThreshold: p.L0StopWritesThreshold,
+					DisableWAL:            p.DisableWAL,
+				}
+				if p.CacheSize != 0 {
+					opts.Cache = pebble.NewCache(int64(p.CacheSize))
+				}
+				if p.MaxConcurrentCompactions != 0 {
+					opts.MaxConcurrentCompactions = func() int { return p.MaxConcurrentCompactions }
+				}
+				opts.EnsureDefaults()
+
+				pds, err := pebbleds.NewDatastore(dir, opts)
+				if opts.Cache != nil {
+					// pebble holds its own reference
+					opts.Cache.Unref()
+				}
+				if err != nil {
+					return nil, nil, err
+				}
+				// go-ds-pebble never syncs writes, its Sync syncs the WAL
+				return withSync(pds, sync(fast)), pds, nil
+			}, nil
+		},
+		Destroy:  p.destroy,
+		SyncMode: sync,
+	}
+}
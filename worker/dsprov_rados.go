@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"io"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-ds-bench/options"
+)
+
+// rados needs librados, build with -tags nautilus. Without it, the candidate
+// runs against an in-process fake cluster (see dsprov_rados_fake.go).
+func init() {
+	RegisterCandidate("rados", CandidateRados, radosParams{})
+}
+
+type radosParams struct {
+	CephConfig string `param:"required" doc:"path to ceph.conf"`
+	CephPool   string `param:"required"`
+}
+
+var CandidateRados = func(spec options.WorkerDatastore) CandidateDatastore {
+	p := radosParams{}
+	perr := options.DecodeParams(spec.Params, &p)
+
+	return CandidateDatastore{
+		Create: func() (func(bool) (ds.Batching, io.Closer, error), error) {
+			if perr != nil {
+				return nil, perr
+			}
+
+			return func(bool) (ds.Batching, io.Closer, error) {
+				ds, err := openRados(p)
+				if err != nil {
+					return nil, nil, err
+				}
+				return ds, ds, nil
+			}, nil
+		},
+		Destroy: func() {
+			if perr == nil {
+				destroyRados(p)
+			}
+		},
+		// rados acknowledges writes once they're stored by all replicas,
+		// priming can't go any faster
+		SyncMode: func(bool) string {
+			return options.SyncPerOp
+		},
+	}
+}
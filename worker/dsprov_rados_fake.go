@@ -0,0 +1,23 @@
+//go:build !nautilus
+// +build !nautilus
+
+package worker
+
+import (
+	ds "github.com/ipfs/go-datastore"
+
+	"github.com/ipfs/go-ds-bench/worker/fakerados"
+	"github.com/ipfs/go-ds-bench/worker/helpers"
+)
+
+// openRados opens the fake cluster named by CephConfig, the config file isn't
+// read. Results are marked, they're recorded under the same datastore names as
+// real rados ones.
+func openRados(p radosParams) (ds.Batching, error) {
+	helpers.ReportConfig("rados-backend", "fake")
+	return fakerados.NewDatastore(p.CephConfig, p.CephPool)
+}
+
+func destroyRados(p radosParams) {
+	fakerados.Connect(p.CephConfig).DeletePool(p.CephPool)
+}
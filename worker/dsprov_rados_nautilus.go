@@ -0,0 +1,17 @@
+//go:build nautilus
+// +build nautilus
+
+package worker
+
+import (
+	ds "github.com/ipfs/go-datastore"
+
+	radosds "github.com/coryschwartz/go-ds-rados"
+)
+
+func openRados(p radosParams) (ds.Batching, error) {
+	return radosds.NewDatastore(p.CephConfig, p.CephPool)
+}
+
+// destroyRados leaves the pool alone, it's managed by the cluster admin
+func destroyRados(p radosParams) {}
@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"io"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-ds-bench/options"
+	"github.com/ipfs/go-ds-bench/worker/remoteds"
+)
+
+func init() {
+	RegisterCandidate("remote", CandidateRemote, remoteParams{})
+}
+
+type remoteParams struct {
+	URL string `param:"required" doc:"base url of a datastore server, like http://10.0.0.1:8080, see worker.test -serve"`
+}
+
+var CandidateRemote = func(spec options.WorkerDatastore) CandidateDatastore {
+	p := remoteParams{}
+	perr := options.DecodeParams(spec.Params, &p)
+	c := remoteds.NewClient(p.URL)
+
+	return CandidateDatastore{
+		Create: func() (func(bool) (ds.Batching, io.Closer, error), error) {
+			if perr != nil {
+				return nil, perr
+			}
+
+			if err := c.Create(); err != nil {
+				return nil, err
+			}
+
+			return func(fast bool) (ds.Batching, io.Closer, error) {
+				d, err := c.Open(fast)
+				if err != nil {
+					return nil, nil, err
+				}
+				return d, d, nil
+			}, nil
+		},
+		Destroy: func() {
+			if perr == nil {
+				c.Destroy()
+			}
+		},
+		// decided by the server, asked for it so results carry it like
+		// local ones. An unreachable server fails Create, no mode is
+		// printed for it.
+		SyncMode: func(fast bool) string {
+			if perr != nil {
+				return ""
+			}
+			mode, _ := c.SyncMode(fast)
+			return mode
+		},
+	}
+}
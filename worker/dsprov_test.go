@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-ds-bench/options"
+)
+
+var syncModes = []string{options.SyncNone, options.SyncPerOp, options.SyncPerBatch}
+
+func TestSyncModes(t *testing.T) {
+	cases := []struct {
+		name      string
+		supported []string
+		emulated  []string // modes provided by withSync
+	}{
+		{"flatfs", []string{options.SyncNone, options.SyncPerOp}, nil},
+		{"leveldb", []string{options.SyncNone, options.SyncPerOp}, nil},
+		{"bolt", syncModes, []string{options.SyncPerBatch}},
+		{"badger", syncModes, []string{options.SyncPerBatch}},
+		{"pebble", syncModes, []string{options.SyncPerOp, options.SyncPerBatch}},
+	}
+
+	for _, c := range cases {
+		if _, ok := candidate(c.name); !ok {
+			t.Logf("%s: not built", c.name)
+			continue
+		}
+
+		for _, mode := range syncModes {
+			spec := options.WorkerDatastore{
+				Type: c.name,
+				Name: c.name,
+				Params: map[string]interface{}{
+					"DataDir":  t.TempDir(),
+					"SyncMode": mode,
+				},
+			}
+			cand := CandidateDs(spec)
+
+			newStore, err := cand.Create()
+			if !contains(c.supported, mode) {
+				if err == nil {
+					t.Errorf("%s: expected error for unsupported mode %s", c.name, mode)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s %s: %s", c.name, mode, err)
+			}
+
+			if m := cand.SyncMode(true); m != options.SyncNone {
+				t.Errorf("%s %s: primes with %s", c.name, mode, m)
+			}
+			if m := cand.SyncMode(false); m != mode {
+				t.Errorf("%s %s: measures with %s", c.name, mode, m)
+			}
+
+			for _, fast := range []bool{true, false} {
+				d, closer, err := newStore(fast)
+				if err != nil {
+					t.Fatalf("%s %s: %s", c.name, mode, err)
+				}
+
+				var wrapped bool
+				switch d.(type) {
+				case *syncingDs, *syncingTxnDs:
+					wrapped = true
+				}
+				if want := contains(c.emulated, cand.SyncMode(fast)); wrapped != want {
+					t.Errorf("%s %s fast=%t: sync emulated: %t, expected %t", c.name, mode, fast, wrapped, want)
+				}
+				closer.Close()
+			}
+			cand.Destroy()
+		}
+	}
+}
+
+// TestNativeSync checks backend sync flags for each phase mode, badger is
+// checked in dsprov_badger_test.go as it can be left out
+func TestNativeSync(t *testing.T) {
+	cases := []struct {
+		mode            string
+		flatfs, leveldb bool // sync enabled
+		boltNoSync      bool
+	}{
+		{options.SyncNone, false, false, true},
+		{options.SyncPerOp, true, true, false},
+		{options.SyncPerBatch, false, false, true},
+	}
+
+	for _, c := range cases {
+		if flatfsSync(c.mode) != c.flatfs {
+			t.Errorf("flatfs %s: sync=%t", c.mode, flatfsSync(c.mode))
+		}
+		if leveldbNoSync(c.mode) == c.leveldb {
+			t.Errorf("leveldb %s: NoSync=%t", c.mode, leveldbNoSync(c.mode))
+		}
+		if boltNoSync(c.mode) != c.boltNoSync {
+			t.Errorf("bolt %s: NoSync=%t", c.mode, boltNoSync(c.mode))
+		}
+	}
+}
+
+func TestFlatfsKeys(t *testing.T) {
+	flat := options.WorkerDatastore{Type: "flatfs"}
+	mounted := options.WorkerDatastore{Type: "flatfs", Wrappers: []options.WrapperSpec{{Type: "mount", Params: map[string]interface{}{"Prefix": "/blocks"}}}}
+
+	cases := []struct {
+		spec options.WorkerDatastore
+		opt  options.BenchOptions
+		ok   bool
+	}{
+		{flat, options.BenchOptions{KeyFormat: options.KeyFormatCid}, true},
+		{flat, options.BenchOptions{KeyPrefix: "/blocks"}, false},
+		{flat, options.BenchOptions{KeyDepth: 1}, false},
+		{mounted, options.BenchOptions{KeyPrefix: "/blocks"}, true},
+		{mounted, options.BenchOptions{KeyPrefix: "/blocks", KeyDepth: 1}, false},
+		{options.WorkerDatastore{Type: "leveldb"}, options.BenchOptions{KeyPrefix: "/blocks", KeyDepth: 1}, true},
+	}
+	for _, c := range cases {
+		if err := flatfsKeys(c.spec, c.opt); (err == nil) != c.ok {
+			t.Errorf("%s %s: %v", c.spec.Type, c.opt.Descriptor(), err)
+		}
+	}
+}
+
+func TestLegacySyncParam(t *testing.T) {
+	mode, err := diskParams{Sync: true}.syncMode(syncModes...)
+	if err != nil || mode != options.SyncPerOp {
+		t.Errorf("Sync should mean per-op, got %s, %v", mode, err)
+	}
+	if _, err := (diskParams{Sync: true, SyncMode: options.SyncNone}).syncMode(syncModes...); err == nil {
+		t.Error("expected error for conflicting Sync and SyncMode")
+	}
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package fakerados
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	datastore "github.com/ipfs/go-datastore"
+	dstest "github.com/ipfs/go-datastore/test"
+)
+
+func TestSuite(t *testing.T) {
+	d, err := NewDatastore("suite.conf", "bench")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstest.SubtestAll(t, d)
+}
+
+func TestReopen(t *testing.T) {
+	ctx := context.Background()
+	k := datastore.NewKey("/a")
+	v := bytes.Repeat([]byte("abc"), 1000) // multiple read chunks
+
+	d, err := NewDatastore("reopen.conf", "bench")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Put(ctx, k, v); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+
+	d, err = NewDatastore("reopen.conf", "bench")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.Get(ctx, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, v) {
+		t.Fatal("value changed after reopen")
+	}
+
+	if other, _ := NewDatastore("other.conf", "bench"); other != nil {
+		if has, _ := other.Has(ctx, k); has {
+			t.Error("clusters should be separate")
+		}
+	}
+
+	Connect("reopen.conf").DeletePool("bench")
+	if _, err := d.Get(ctx, k); err != datastore.ErrNotFound {
+		t.Errorf("expected ErrNotFound after deleting pool, got %v", err)
+	}
+}
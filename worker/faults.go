@@ -0,0 +1,274 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/retrystore"
+	"github.com/ipfs/go-ds-bench/options"
+)
+
+func init() {
+	RegisterWrapper("faults", WrapperFaults, faultDefaults)
+	RegisterWrapper("retry", WrapperRetry, retryDefaults)
+}
+
+// ErrInjected is returned by operations the faults wrapper fails
+var ErrInjected = errors.New("injected fault")
+
+// fault injection points
+const (
+	faultPut    = "put"    // Put and Delete
+	faultGet    = "get"    // Get, GetSize and Has
+	faultCommit = "commit" // batch commits
+)
+
+type faultParams struct {
+	Latency   string  `doc:"delay added to affected operations, like 2ms"`
+	Jitter    string  `doc:"random delay added on top of Latency, up to this"`
+	ErrorRate float64 `doc:"fraction of affected operations failing with an injected error, after delays"`
+	StallRate float64 `doc:"fraction of affected operations stalling for Stall"`
+	Stall     string  `doc:"duration of stalls"`
+	Ops       string  `doc:"comma separated operations faults apply to: put, get, commit"`
+	Seed      int     `doc:"random seed, runs with the same seed and operations inject the same faults"`
+}
+
+var faultDefaults = faultParams{
+	Stall: "1s",
+	Ops:   strings.Join([]string{faultPut, faultGet, faultCommit}, ","),
+	Seed:  1,
+}
+
+// faults decides delays and errors of operations
+type faults struct {
+	latency, jitter, stall time.Duration
+	errorRate, stallRate   float64
+	ops                    map[string]bool
+
+	lk  sync.Mutex
+	rng *rand.Rand
+}
+
+// parseDuration parses a duration param, empty means zero
+func parseDuration(name, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s must not be negative, got %s", name, s)
+	}
+	return d, nil
+}
+
+func newFaults(p faultParams) (*faults, error) {
+	f := &faults{
+		errorRate: p.ErrorRate,
+		stallRate: p.StallRate,
+		ops:       map[string]bool{},
+		rng:       rand.New(rand.NewSource(int64(p.Seed))),
+	}
+
+	var err error
+	if f.latency, err = parseDuration("Latency", p.Latency); err != nil {
+		return nil, err
+	}
+	if f.jitter, err = parseDuration("Jitter", p.Jitter); err != nil {
+		return nil, err
+	}
+	if f.stall, err = parseDuration("Stall", p.Stall); err != nil {
+		return nil, err
+	}
+	if f.errorRate < 0 || f.errorRate > 1 || f.stallRate < 0 || f.stallRate > 1 {
+		return nil, fmt.Errorf("ErrorRate and StallRate must be in [0, 1], got %g, %g", f.errorRate, f.stallRate)
+	}
+
+	for _, op := range strings.Split(p.Ops, ",") {
+		switch op = strings.TrimSpace(op); op {
+		case faultPut, faultGet, faultCommit:
+			f.ops[op] = true
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown fault op '%s', expected put, get or commit", op)
+		}
+	}
+	return f, nil
+}
+
+// inject delays an operation, returning ErrInjected if it should fail
+func (f *faults) inject(op string) error {
+	if !f.ops[op] {
+		return nil
+	}
+
+	f.lk.Lock()
+	delay := f.latency
+	if f.jitter > 0 {
+		delay += time.Duration(f.rng.Int63n(int64(f.jitter)))
+	}
+	if f.rng.Float64() < f.stallRate {
+		delay += f.stall
+	}
+	fail := f.rng.Float64() < f.errorRate
+	f.lk.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if fail {
+		return ErrInjected
+	}
+	return nil
+}
+
+// faultyDs injects faults into operations of a datastore
+type faultyDs struct {
+	ds.Batching
+	f *faults
+}
+
+func (d *faultyDs) Put(ctx context.Context, key ds.Key, value []byte) error {
+	if err := d.f.inject(faultPut); err != nil {
+		return err
+	}
+	return d.Batching.Put(ctx, key, value)
+}
+
+func (d *faultyDs) Delete(ctx context.Context, key ds.Key) error {
+	if err := d.f.inject(faultPut); err != nil {
+		return err
+	}
+	return d.Batching.Delete(ctx, key)
+}
+
+func (d *faultyDs) Get(ctx context.Context, key ds.Key) ([]byte, error) {
+	if err := d.f.inject(faultGet); err != nil {
+		return nil, err
+	}
+	return d.Batching.Get(ctx, key)
+}
+
+func (d *faultyDs) GetSize(ctx context.Context, key ds.Key) (int, error) {
+	if err := d.f.inject(faultGet); err != nil {
+		return -1, err
+	}
+	return d.Batching.GetSize(ctx, key)
+}
+
+func (d *faultyDs) Has(ctx context.Context, key ds.Key) (bool, error) {
+	if err := d.f.inject(faultGet); err != nil {
+		return false, err
+	}
+	return d.Batching.Has(ctx, key)
+}
+
+func (d *faultyDs) Batch(ctx context.Context) (ds.Batch, error) {
+	b, err := d.Batching.Batch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyBatch{Batch: b, f: d.f}, nil
+}
+
+type faultyBatch struct {
+	ds.Batch
+	f *faults
+}
+
+func (b *faultyBatch) Commit(ctx context.Context) error {
+	if err := b.f.inject(faultCommit); err != nil {
+		return err
+	}
+	return b.Batch.Commit(ctx)
+}
+
+// WrapperFaults injects latency, stalls and errors into operations, to
+// measure degraded backends
+var WrapperFaults = func(spec options.WrapperSpec) (func(ds.Batching) (ds.Batching, error), error) {
+	p := faultDefaults
+	if err := options.DecodeParams(spec.Params, &p); err != nil {
+		return nil, err
+	}
+	// validate before any datastore gets wrapped
+	if _, err := newFaults(p); err != nil {
+		return nil, err
+	}
+
+	return func(d ds.Batching) (ds.Batching, error) {
+		f, err := newFaults(p)
+		if err != nil {
+			return nil, err
+		}
+		return &faultyDs{Batching: d, f: f}, nil
+	}, nil
+}
+
+type retryParams struct {
+	Retries int    `doc:"attempts after the first failed one"`
+	Delay   string `doc:"base delay between attempts, multiplied by the attempt number"`
+}
+
+var retryDefaults = retryParams{Retries: 3, Delay: "1ms"}
+
+// retrying retries batch commits too, retrystore only wraps datastore calls
+type retrying struct {
+	*retrystore.Datastore
+}
+
+func (d *retrying) Batch(ctx context.Context) (ds.Batch, error) {
+	b, err := d.Datastore.Batch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &retryingBatch{Batch: b, d: d.Datastore}, nil
+}
+
+type retryingBatch struct {
+	ds.Batch
+	d *retrystore.Datastore
+}
+
+func (b *retryingBatch) Commit(ctx context.Context) error {
+	err := b.Batch.Commit(ctx)
+	for i := 0; err != nil && b.d.TempErrFunc(err) && i < b.d.Retries; i++ {
+		time.Sleep(time.Duration(i+1) * b.d.Delay)
+		err = b.Batch.Commit(ctx)
+	}
+	return err
+}
+
+// WrapperRetry retries failed operations, like retry layers over flaky
+// backends do
+var WrapperRetry = func(spec options.WrapperSpec) (func(ds.Batching) (ds.Batching, error), error) {
+	p := retryDefaults
+	if err := options.DecodeParams(spec.Params, &p); err != nil {
+		return nil, err
+	}
+	if p.Retries < 0 {
+		return nil, fmt.Errorf("Retries must not be negative, got %d", p.Retries)
+	}
+	delay, err := parseDuration("Delay", p.Delay)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(d ds.Batching) (ds.Batching, error) {
+		return &retrying{&retrystore.Datastore{
+			TempErrFunc: func(err error) bool {
+				return err != ds.ErrNotFound
+			},
+			Retries:  p.Retries,
+			Delay:    delay,
+			Batching: d,
+		}}, nil
+	}, nil
+}
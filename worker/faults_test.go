@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-ds-bench/options"
+)
+
+func TestFaults(t *testing.T) {
+	ctx := context.Background()
+
+	wrap, err := wrapChain([]options.WrapperSpec{
+		{Type: "faults", Params: map[string]interface{}{"ErrorRate": 0.5, "Ops": "put,commit"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := wrap(ds.NewMapDatastore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var failed int
+	for i := 0; i < 1000; i++ {
+		if err := d.Put(ctx, ds.NewKey("a"), []byte("a")); err == ErrInjected {
+			failed++
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if failed < 400 || failed > 600 {
+		t.Errorf("%d of 1000 puts failed, expected about 500", failed)
+	}
+
+	// gets aren't affected
+	for i := 0; i < 100; i++ {
+		if _, err := d.Get(ctx, ds.NewKey("a")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFaultsRetry(t *testing.T) {
+	ctx := context.Background()
+
+	wrap, err := wrapChain([]options.WrapperSpec{
+		{Type: "faults", Params: map[string]interface{}{"ErrorRate": 0.2}},
+		{Type: "retry", Params: map[string]interface{}{"Retries": 20, "Delay": "0s"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := wrap(ds.NewMapDatastore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		if err := d.Put(ctx, ds.NewKey("a"), []byte("a")); err != nil {
+			t.Fatal(err)
+		}
+		b, err := d.Batch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Put(ctx, ds.NewKey("b"), []byte("b")); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := d.Get(ctx, ds.NewKey("missing")); err != ds.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFaultsParams(t *testing.T) {
+	for _, params := range []map[string]interface{}{
+		{"ErrorRate": 2.0},
+		{"Latency": "fast"},
+		{"Jitter": "-1ms"},
+		{"Ops": "put,scan"},
+	} {
+		if _, err := wrapChain([]options.WrapperSpec{{Type: "faults", Params: params}}); err == nil {
+			t.Errorf("expected error for %v", params)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package helpers
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+var (
+	configLk sync.Mutex
+	config   = map[string]string{}
+)
+
+// ReportConfig records a benchfmt configuration line, like "conflicts-per-op:
+// 0.2", kept by master with the result. Lines are printed by FlushConfig once
+// the benchmark is done, printing them while it runs would break the result
+// line. Later reports of a key replace earlier ones.
+func ReportConfig(key string, value interface{}) {
+	configLk.Lock()
+	defer configLk.Unlock()
+
+	config[key] = fmt.Sprint(value)
+}
+
+// FlushConfig prints reported configuration lines to w, sorted by key
+func FlushConfig(w io.Writer) {
+	configLk.Lock()
+	defer configLk.Unlock()
+
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s: %s\n", k, config[k])
+	}
+	config = map[string]string{}
+}
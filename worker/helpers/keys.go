@@ -0,0 +1,265 @@
+package helpers
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/ipfs/go-ds-bench/options"
+
+	ds "github.com/ipfs/go-datastore"
+)
+
+const (
+	defaultKeySize = 32
+
+	defaultZipfTheta       = 0.99
+	defaultHotspotFraction = 0.2
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// KeySpace maps record indexes to datastore keys. Keys look random, but are
+// stable across runs, so priming code and benchmarks can refer to the same
+// records by index.
+type KeySpace struct {
+	format string
+	size   int
+	depth  int
+	prefix string
+}
+
+func NewKeySpace(opt options.BenchOptions) (*KeySpace, error) {
+	ks := &KeySpace{
+		format: opt.KeyFormat,
+		size:   opt.KeySize,
+		depth:  opt.KeyDepth,
+	}
+
+	switch ks.format {
+	case "":
+		ks.format = options.KeyFormatHex
+	case options.KeyFormatHex:
+	case options.KeyFormatCid, options.KeyFormatCidV1:
+		if ks.size != 0 {
+			return nil, fmt.Errorf("key size can't be set for '%s' keys", ks.format)
+		}
+	default:
+		return nil, fmt.Errorf("unknown key format: '%s'", opt.KeyFormat)
+	}
+
+	if ks.size == 0 {
+		ks.size = defaultKeySize
+	}
+	if ks.size < 0 || ks.depth < 0 {
+		return nil, fmt.Errorf("invalid key size %d / depth %d", ks.size, ks.depth)
+	}
+
+	if opt.KeyPrefix != "" {
+		ks.prefix = ds.NewKey(opt.KeyPrefix).String()
+	}
+
+	return ks, nil
+}
+
+func (ks *KeySpace) Key(i int) ds.Key {
+	var name string
+	switch ks.format {
+	case options.KeyFormatCid:
+		// sha2-256 multihash
+		name = b32.EncodeToString(append([]byte{0x12, 0x20}, keyBytes(i, 32)...))
+	case options.KeyFormatCidV1:
+		// CIDv1, raw codec, sha2-256 multihash
+		name = b32.EncodeToString(append([]byte{0x01, 0x55, 0x12, 0x20}, keyBytes(i, 32)...))
+	default:
+		name = strings.ToUpper(hex.EncodeToString(keyBytes(i, (ks.size+1)/2)))[:ks.size]
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(ks.prefix) + len(name) + ks.depth + 1)
+	sb.WriteString(ks.prefix)
+	for d := 0; d < ks.depth && len(name) > 2; d++ {
+		sb.WriteByte('/')
+		sb.WriteString(name[:2])
+		name = name[2:]
+	}
+	sb.WriteByte('/')
+	sb.WriteString(name)
+
+	return ds.RawKey(sb.String())
+}
+
+// keyBytes returns n pseudo-random bytes derived from i
+func keyBytes(i int, n int) []byte {
+	out := make([]byte, 0, n+8)
+	x := uint64(i)
+	for len(out) < n {
+		x = splitmix64(x)
+		out = binary.BigEndian.AppendUint64(out, x)
+	}
+	return out[:n]
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// KeyDist picks indexes of keys to access, in range [0, n), where n-1 is the
+// most recently inserted key
+type KeyDist interface {
+	Next() int
+}
+
+func NewKeyDist(opt options.BenchOptions, n int) (KeyDist, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("key distribution needs at least one key, got %d", n)
+	}
+
+	rng := rand.New(rand.NewSource(int64(n)))
+
+	switch opt.KeyDistribution {
+	case "", options.KeyDistSequential:
+		return &seqDist{n: n}, nil
+	case options.KeyDistUniform:
+		return &uniformDist{n: n, rng: rng}, nil
+	case options.KeyDistZipfian, options.KeyDistLatest:
+		theta := opt.ZipfTheta
+		if theta == 0 {
+			theta = defaultZipfTheta
+		}
+		if theta <= 0 || theta >= 1 {
+			return nil, fmt.Errorf("zipf theta must be in (0, 1), got %f", theta)
+		}
+
+		zipf := newZipfDist(n, theta, rng)
+		if opt.KeyDistribution == options.KeyDistLatest {
+			return &latestDist{zipf: zipf}, nil
+		}
+		return zipf, nil
+	case options.KeyDistHotspot:
+		hot := opt.HotspotFraction
+		if hot == 0 {
+			hot = defaultHotspotFraction
+		}
+		if hot <= 0 || hot >= 1 {
+			return nil, fmt.Errorf("hotspot fraction must be in (0, 1), got %f", hot)
+		}
+		return newHotspotDist(n, hot, rng), nil
+	default:
+		return nil, fmt.Errorf("unknown key distribution: '%s'", opt.KeyDistribution)
+	}
+}
+
+type seqDist struct {
+	n, i int
+}
+
+func (d *seqDist) Next() int {
+	i := d.i
+	d.i = (d.i + 1) % d.n
+	return i
+}
+
+type uniformDist struct {
+	n   int
+	rng *rand.Rand
+}
+
+func (d *uniformDist) Next() int {
+	return d.rng.Intn(d.n)
+}
+
+// zipfDist is the zipfian generator from YCSB (Gray et al., "Quickly
+// Generating Billion-Record Synthetic Databases"). Unlike rand.Zipf it accepts
+// theta < 1. Index 0 is the most popular one.
+type zipfDist struct {
+	n     int
+	theta float64
+	alpha float64
+	zetan float64
+	eta   float64
+	rng   *rand.Rand
+}
+
+func newZipfDist(n int, theta float64, rng *rand.Rand) *zipfDist {
+	zeta2 := zeta(2, theta)
+	zetan := zeta(n, theta)
+
+	return &zipfDist{
+		n:     n,
+		theta: theta,
+		alpha: 1 / (1 - theta),
+		zetan: zetan,
+		eta:   (1 - math.Pow(2/float64(n), 1-theta)) / (1 - zeta2/zetan),
+		rng:   rng,
+	}
+}
+
+func zeta(n int, theta float64) float64 {
+	var sum float64
+	for i := 1; i <= n; i++ {
+		sum += 1 / math.Pow(float64(i), theta)
+	}
+	return sum
+}
+
+func (d *zipfDist) Next() int {
+	u := d.rng.Float64()
+	uz := u * d.zetan
+
+	if uz < 1 {
+		return 0
+	}
+	if uz < 1+math.Pow(0.5, d.theta) && d.n > 1 {
+		return 1
+	}
+
+	i := int(float64(d.n) * math.Pow(d.eta*u-d.eta+1, d.alpha))
+	if i >= d.n {
+		i = d.n - 1
+	}
+	return i
+}
+
+type latestDist struct {
+	zipf *zipfDist
+}
+
+func (d *latestDist) Next() int {
+	return d.zipf.n - 1 - d.zipf.Next()
+}
+
+// hotspotDist sends 1-hot of accesses to the first hot*n keys
+type hotspotDist struct {
+	n, hotN int
+	hot     float64
+	rng     *rand.Rand
+}
+
+func newHotspotDist(n int, hot float64, rng *rand.Rand) *hotspotDist {
+	hotN := int(float64(n) * hot)
+	if hotN < 1 {
+		hotN = 1
+	}
+
+	return &hotspotDist{
+		n:    n,
+		hotN: hotN,
+		hot:  hot,
+		rng:  rng,
+	}
+}
+
+func (d *hotspotDist) Next() int {
+	if d.hotN == d.n || d.rng.Float64() < 1-d.hot {
+		return d.rng.Intn(d.hotN)
+	}
+	return d.hotN + d.rng.Intn(d.n-d.hotN)
+}
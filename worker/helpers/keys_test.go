@@ -0,0 +1,93 @@
+package helpers
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/ipfs/go-ds-bench/options"
+)
+
+func TestKeySpace(t *testing.T) {
+	cases := []struct {
+		opt options.BenchOptions
+		re  string
+	}{
+		{options.BenchOptions{}, `^/[0-9A-F]{32}$`},
+		{options.BenchOptions{KeySize: 100}, `^/[0-9A-F]{100}$`},
+		{options.BenchOptions{KeySize: 7, KeyDepth: 2, KeyPrefix: "/a/b"}, `^/a/b/[0-9A-F]{2}/[0-9A-F]{2}/[0-9A-F]{3}$`},
+		{options.BenchOptions{KeyFormat: options.KeyFormatCid, KeyPrefix: "blocks"}, `^/blocks/CIQ[A-Z2-7]{52}$`},
+		{options.BenchOptions{KeyFormat: options.KeyFormatCidV1}, `^/AFKREI[A-Z2-7]{52}$`},
+	}
+
+	for _, c := range cases {
+		ks, err := NewKeySpace(c.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ks.Key(42) != ks.Key(42) {
+			t.Fatal("keys should be stable")
+		}
+		if ks.Key(1) == ks.Key(2) {
+			t.Fatal("keys should differ")
+		}
+		if k := ks.Key(1).String(); !regexp.MustCompile(c.re).MatchString(k) {
+			t.Errorf("key %s doesn't match %s", k, c.re)
+		}
+	}
+
+	if _, err := NewKeySpace(options.BenchOptions{KeyFormat: options.KeyFormatCid, KeySize: 10}); err == nil {
+		t.Error("expected error for sized cid keys")
+	}
+}
+
+func TestKeyDistRange(t *testing.T) {
+	n := 100
+
+	for _, d := range []string{"", options.KeyDistSequential, options.KeyDistUniform, options.KeyDistZipfian, options.KeyDistHotspot, options.KeyDistLatest} {
+		dist, err := NewKeyDist(options.BenchOptions{KeyDistribution: d}, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		counts := make([]int, n)
+		for i := 0; i < 100*n; i++ {
+			k := dist.Next()
+			if k < 0 || k >= n {
+				t.Fatalf("%s: index %d out of range", d, k)
+			}
+			counts[k]++
+		}
+
+		switch d {
+		case options.KeyDistZipfian:
+			if counts[0] < counts[n-1]*10 {
+				t.Errorf("zipfian not skewed: first %d, last %d", counts[0], counts[n-1])
+			}
+		case options.KeyDistLatest:
+			if counts[n-1] < counts[0]*10 {
+				t.Errorf("latest not skewed: first %d, last %d", counts[0], counts[n-1])
+			}
+		case options.KeyDistHotspot:
+			hot := 0
+			for _, c := range counts[:n/5] {
+				hot += c
+			}
+			if hot < 75*n || hot > 85*n {
+				t.Errorf("expected ~80%% of accesses to hot keys, got %d/%d", hot, 100*n)
+			}
+		}
+	}
+}
+
+func TestKeyDistInvalid(t *testing.T) {
+	if _, err := NewKeyDist(options.BenchOptions{KeyDistribution: "nope"}, 10); err == nil {
+		t.Error("expected error for unknown distribution")
+	}
+	if _, err := NewKeyDist(options.BenchOptions{KeyDistribution: options.KeyDistZipfian, ZipfTheta: 1}, 10); err == nil {
+		t.Error("expected error for theta=1")
+	}
+	if _, err := NewKeyDist(options.BenchOptions{}, 0); err == nil {
+		t.Error("expected error for empty key set")
+	}
+}
@@ -3,8 +3,14 @@ package helpers
 import (
 	"fmt"
 	"math/rand"
+
+	"github.com/ipfs/go-ds-bench/options"
 )
 
+// valueSegment is the granularity of compressible values, each segment starts
+// with random bytes followed by zeroes
+const valueSegment = 256
+
 var (
 	preRandom []byte
 	pos       int
@@ -20,19 +26,70 @@ func init() {
 	pos = 0
 }
 
+// RandomBuf returns req incompressible bytes. Buffers up to 8MiB share memory
+// with the pre-generated buffer, larger ones are allocated and filled with
+// fresh random bytes, copies of the pre-generated ones would be found by
+// compressors with large windows.
 func RandomBuf(req int) []byte {
 	if req > len(preRandom) {
-		fmt.Printf("req %d, len %d\n", req, len(preRandom))
-		panic("aka rand: requested len too long")
+		buf := make([]byte, req)
+		if _, err := rand.Read(buf); err != nil {
+			panic(err)
+		}
+		return buf
 	}
 
-	if req+pos >= len(preRandom) {
+	if req+pos > len(preRandom) {
 		pos = 0
 	}
 
+	// don't reuse bytes of recent buffers, compressors would find them
 	defer func() {
-		pos++
+		pos += req
 	}()
 
 	return preRandom[pos : pos+req]
 }
+
+// ValueGen generates record values compressible roughly by the ratio set in
+// BenchOptions.CompressionRatio
+type ValueGen struct {
+	randPerSegment int
+}
+
+func NewValueGen(opt options.BenchOptions) (*ValueGen, error) {
+	ratio := opt.CompressionRatio
+	if ratio == 0 {
+		ratio = 1
+	}
+	if ratio < 1 {
+		return nil, fmt.Errorf("compression ratio must be >= 1, got %f", ratio)
+	}
+
+	randPerSegment := int(float64(valueSegment) / ratio)
+	if randPerSegment < 1 {
+		randPerSegment = 1
+	}
+
+	return &ValueGen{
+		randPerSegment: randPerSegment,
+	}, nil
+}
+
+// Value returns a value of given size. Incompressible values may share memory
+// like RandomBuf, so they must not be modified.
+func (g *ValueGen) Value(size int) []byte {
+	if g.randPerSegment >= valueSegment {
+		return RandomBuf(size)
+	}
+
+	buf := make([]byte, size)
+	for off := 0; off < size; off += valueSegment {
+		end := off + g.randPerSegment
+		if end > size {
+			end = size
+		}
+		copy(buf[off:end], RandomBuf(end-off))
+	}
+	return buf
+}
@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"bytes"
+	"compress/flate"
+	"testing"
+
+	"github.com/ipfs/go-ds-bench/options"
+
+	ds "github.com/ipfs/go-datastore"
+)
+
+func compressedSize(t *testing.T, b []byte) int {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Len()
+}
+
+func TestValueGenRatio(t *testing.T) {
+	for _, ratio := range []float64{0, 1, 2, 4} {
+		vg, err := NewValueGen(options.BenchOptions{CompressionRatio: ratio})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var all []byte
+		for i := 0; i < 64; i++ {
+			all = append(all, vg.Value(4096)...)
+		}
+
+		expect := ratio
+		if expect == 0 {
+			expect = 1
+		}
+
+		got := float64(len(all)) / float64(compressedSize(t, all))
+		if got < expect*0.8 || got > expect*1.2 {
+			t.Errorf("ratio %f: compressed by %f", ratio, got)
+		}
+	}
+}
+
+func TestValueGenLarge(t *testing.T) {
+	for _, ratio := range []float64{1, 3} {
+		vg, err := NewValueGen(options.BenchOptions{CompressionRatio: ratio})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if l := len(vg.Value(20 << 20)); l != 20<<20 {
+			t.Fatalf("unexpected value length %d", l)
+		}
+	}
+
+	if _, err := NewValueGen(options.BenchOptions{CompressionRatio: 0.5}); err == nil {
+		t.Error("expected error for ratio < 1")
+	}
+}
+
+func TestRandomBufLarge(t *testing.T) {
+	buf := RandomBuf(len(preRandom) + 1<<20)
+
+	// chunks of large buffers mustn't repeat the pre-generated bytes
+	for _, off := range []int{0, len(preRandom) / 2, len(preRandom), len(buf) - 64} {
+		if bytes.Contains(preRandom, buf[off:off+64]) {
+			t.Errorf("bytes at %d are copied from the pre-generated buffer", off)
+		}
+	}
+}
+
+func TestKeyedValue(t *testing.T) {
+	vg, err := NewValueGen(options.BenchOptions{CompressionRatio: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := ds.NewKey("a"), ds.NewKey("b")
+	va := vg.KeyedValue(a, 1000)
+	if !bytes.Equal(va, vg.KeyedValue(a, 1000)) {
+		t.Fatal("keyed values differ")
+	}
+	if bytes.Equal(va, vg.KeyedValue(b, 1000)) {
+		t.Fatal("values of different keys are equal")
+	}
+
+	if !vg.Check(a, va, 1000) {
+		t.Error("value doesn't check")
+	}
+	if vg.Check(b, va, 1000) || vg.Check(a, va[:999], 1000) {
+		t.Error("wrong value checks")
+	}
+	va[500] ^= 1
+	if vg.Check(a, va, 1000) {
+		t.Error("corrupted value checks")
+	}
+}
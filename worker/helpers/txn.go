@@ -0,0 +1,33 @@
+package helpers
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	conflictsLk sync.RWMutex
+	conflicts   []error
+)
+
+// RegisterConflictErr registers errors transaction commits fail with when
+// they conflict with concurrent transactions, for backends to call from init
+func RegisterConflictErr(errs ...error) {
+	conflictsLk.Lock()
+	defer conflictsLk.Unlock()
+
+	conflicts = append(conflicts, errs...)
+}
+
+// IsConflict tells if err is a registered transaction conflict error
+func IsConflict(err error) bool {
+	conflictsLk.RLock()
+	defer conflictsLk.RUnlock()
+
+	for _, c := range conflicts {
+		if errors.Is(err, c) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+
+	ds "github.com/ipfs/go-datastore"
+)
+
+// fill fills b with bytes derived from seed, returning the next seed
+func fill(b []byte, seed uint64) uint64 {
+	var w [8]byte
+	for len(b) > 0 {
+		seed = splitmix64(seed)
+		binary.LittleEndian.PutUint64(w[:], seed)
+		b = b[copy(b, w[:]):]
+	}
+	return seed
+}
+
+// KeyedValue returns a value of given size derived from the key, so it can be
+// checked after reading it back in any process. Values are compressible like
+// ones returned by Value.
+func (g *ValueGen) KeyedValue(key ds.Key, size int) []byte {
+	h := fnv.New64a()
+	h.Write(key.Bytes())
+	seed := h.Sum64()
+
+	buf := make([]byte, size)
+	for off := 0; off < size; off += valueSegment {
+		end := off + g.randPerSegment
+		if end > size {
+			end = size
+		}
+		seed = fill(buf[off:end], seed)
+	}
+	return buf
+}
+
+// Check tells if v is the KeyedValue of key with size
+func (g *ValueGen) Check(key ds.Key, v []byte, size int) bool {
+	return len(v) == size && bytes.Equal(v, g.KeyedValue(key, size))
+}
@@ -6,8 +6,8 @@ import (
 )
 
 func TestOptionsSimpleRange(t *testing.T) {
-	start := options.BenchOptions{1, 100, 64}
-	end := options.BenchOptions{1 << 10, 100, 64}
+	start := options.BenchOptions{PrimeRecordCount: 1, RecordSize: 100, BatchSize: 64}
+	end := options.BenchOptions{PrimeRecordCount: 1 << 10, RecordSize: 100, BatchSize: 64}
 
 	opts := options.OptionsRange2pow(start, end, 11)
 	if len(opts) != 11 {
@@ -22,8 +22,8 @@ func TestOptionsSimpleRange(t *testing.T) {
 }
 
 func TestOptionsBoth(t *testing.T) {
-	start := options.BenchOptions{1, 1, 64}
-	end := options.BenchOptions{1 << 10, 1 << 10, 64}
+	start := options.BenchOptions{PrimeRecordCount: 1, RecordSize: 1, BatchSize: 64}
+	end := options.BenchOptions{PrimeRecordCount: 1 << 10, RecordSize: 1 << 10, BatchSize: 64}
 
 	opts := options.OptionsRange2pow(start, end, 11)
 	if len(opts) != 11*11 {
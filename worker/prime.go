@@ -2,16 +2,29 @@ package worker
 
 import (
 	"context"
-	"math/rand"
 	"sync"
 	"testing"
 
+	"github.com/ipfs/go-ds-bench/options"
+	"github.com/ipfs/go-ds-bench/worker/helpers"
+
 	ds "github.com/ipfs/go-datastore"
 )
 
 const primeMaxBatchSize = 1 << 30 // 1 GiB
 
-func PrimeDS(tb testing.TB, store ds.Batching, count, blockSize int) {
+func PrimeDS(tb testing.TB, store ds.Batching, opt options.BenchOptions) {
+	count, blockSize := opt.PrimeRecordCount, opt.RecordSize
+
+	ks, err := helpers.NewKeySpace(opt)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	vg, err := helpers.NewValueGen(opt)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
 	maxBatchCount := primeMaxBatchSize / blockSize
 	if maxBatchCount > 2048 {
 		maxBatchCount = 2048
@@ -25,22 +38,23 @@ func PrimeDS(tb testing.TB, store ds.Batching, count, blockSize int) {
 
 	var wg sync.WaitGroup
 	wg.Add(parallelism)
-	for i := 0; i < parallelism; i++ {
-		go func() {
+	for p := 0; p < parallelism; p++ {
+		go func(p int) {
 			defer wg.Done()
 			ctx := context.Background()
-			buf := make([]byte, blockSize)
 			b, err := store.Batch(ctx)
 			if err != nil {
 				tb.Fatal(err)
 			}
 
 			for i := 0; i < count/parallelism; i++ {
-				_, err := rand.Read(buf)
-				if err != nil {
-					tb.Fatal(err)
+				k := ks.Key(p*(count/parallelism) + i)
+				v := vg.Value(blockSize)
+				if opt.Verify != "" {
+					v = vg.KeyedValue(k, blockSize)
 				}
-				err = b.Put(ctx, ds.RandomKey(), buf)
+
+				err := b.Put(ctx, k, v)
 				if err != nil {
 					tb.Fatal(err)
 				}
@@ -61,7 +75,7 @@ func PrimeDS(tb testing.TB, store ds.Batching, count, blockSize int) {
 			if err != nil {
 				tb.Fatal(err)
 			}
-		}()
+		}(p)
 	}
 	wg.Wait()
 }
@@ -0,0 +1,168 @@
+package worker
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-ds-bench/options"
+)
+
+// CandidateCtor builds a candidate datastore from its spec
+type CandidateCtor func(options.WorkerDatastore) CandidateDatastore
+
+// WrapperCtor validates a wrapper spec, returning a function layering the
+// wrapper over a datastore
+type WrapperCtor func(options.WrapperSpec) (func(ds.Batching) (ds.Batching, error), error)
+
+type registered struct {
+	ctor   CandidateCtor
+	params []options.ParamSpec
+}
+
+type registeredWrapper struct {
+	ctor   WrapperCtor
+	params []options.ParamSpec
+}
+
+var (
+	registryLk sync.RWMutex
+	datastores = map[string]registered{}
+	wrappers   = map[string]registeredWrapper{}
+)
+
+// RegisterCandidate makes a datastore available to benchmarks under name
+// (WorkerDatastore.Type). params is the struct the candidate decodes
+// WorkerDatastore.Params into (see options.DecodeParams), holding default
+// values, or nil if the candidate takes no params.
+//
+// It's meant to be called from init functions, and panics when the name is
+// already taken.
+func RegisterCandidate(name string, ctor CandidateCtor, params interface{}) {
+	registryLk.Lock()
+	defer registryLk.Unlock()
+
+	if ctor == nil {
+		panic("worker: RegisterCandidate ctor is nil")
+	}
+	if _, dup := datastores[name]; dup {
+		panic(fmt.Sprintf("worker: RegisterCandidate called twice for '%s'", name))
+	}
+	datastores[name] = registered{
+		ctor:   ctor,
+		params: options.ParamSchema(params),
+	}
+}
+
+// RegisterWrapper makes a datastore wrapper available under name
+// (WrapperSpec.Type), params are handled like in RegisterCandidate.
+func RegisterWrapper(name string, ctor WrapperCtor, params interface{}) {
+	registryLk.Lock()
+	defer registryLk.Unlock()
+
+	if ctor == nil {
+		panic("worker: RegisterWrapper ctor is nil")
+	}
+	if _, dup := wrappers[name]; dup {
+		panic(fmt.Sprintf("worker: RegisterWrapper called twice for '%s'", name))
+	}
+	wrappers[name] = registeredWrapper{
+		ctor:   ctor,
+		params: options.ParamSchema(params),
+	}
+}
+
+// Candidates returns sorted names of registered datastores
+func Candidates() []string {
+	registryLk.RLock()
+	defer registryLk.RUnlock()
+
+	out := make([]string, 0, len(datastores))
+	for name := range datastores {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// CandidateInfos describes registered datastores and their params, sorted by
+// name
+func CandidateInfos() []options.CandidateInfo {
+	registryLk.RLock()
+	defer registryLk.RUnlock()
+
+	out := make([]options.CandidateInfo, 0, len(datastores))
+	for name, r := range datastores {
+		out = append(out, options.CandidateInfo{
+			Name:   name,
+			Params: r.params,
+		})
+	}
+	sortInfos(out)
+	return out
+}
+
+// Wrappers returns sorted names of registered wrappers
+func Wrappers() []string {
+	registryLk.RLock()
+	defer registryLk.RUnlock()
+
+	out := make([]string, 0, len(wrappers))
+	for name := range wrappers {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// WrapperInfos describes registered wrappers and their params, sorted by name
+func WrapperInfos() []options.CandidateInfo {
+	registryLk.RLock()
+	defer registryLk.RUnlock()
+
+	out := make([]options.CandidateInfo, 0, len(wrappers))
+	for name, r := range wrappers {
+		out = append(out, options.CandidateInfo{
+			Name:   name,
+			Params: r.params,
+		})
+	}
+	sortInfos(out)
+	return out
+}
+
+func sortInfos(infos []options.CandidateInfo) {
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+}
+
+func candidate(name string) (CandidateCtor, bool) {
+	registryLk.RLock()
+	defer registryLk.RUnlock()
+
+	r, ok := datastores[name]
+	return r.ctor, ok
+}
+
+// hasParam tells if the datastore registered under name accepts param
+func hasParam(name, param string) bool {
+	registryLk.RLock()
+	defer registryLk.RUnlock()
+
+	for _, p := range datastores[name].params {
+		if p.Name == param {
+			return true
+		}
+	}
+	return false
+}
+
+func wrapper(name string) (WrapperCtor, bool) {
+	registryLk.RLock()
+	defer registryLk.RUnlock()
+
+	r, ok := wrappers[name]
+	return r.ctor, ok
+}
@@ -0,0 +1,291 @@
+package remoteds
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+)
+
+// Client talks to a remote datastore server
+type Client struct {
+	url  string
+	http *http.Client
+}
+
+// NewClient returns a client for the server at base url, like
+// http://10.0.0.1:8080
+func NewClient(base string) *Client {
+	return &Client{
+		url: strings.TrimSuffix(base, "/"),
+		http: &http.Client{
+			Transport: &http.Transport{
+				// benches may run many operations concurrently, keep
+				// connections around
+				MaxIdleConnsPerHost: 256,
+			},
+		},
+	}
+}
+
+// Create creates the served datastore, a no-op if the server doesn't manage
+// its lifecycle
+func (c *Client) Create() error {
+	return c.lifecycle(context.Background(), "create", nil)
+}
+
+// Open opens the served datastore, in fast mode when priming
+func (c *Client) Open(fast bool) (*Datastore, error) {
+	q := url.Values{}
+	if fast {
+		q.Set("fast", "1")
+	}
+	if err := c.lifecycle(context.Background(), "open", q); err != nil {
+		return nil, err
+	}
+	return &Datastore{c: c}, nil
+}
+
+// Destroy removes the served datastore, a no-op if the server doesn't manage
+// its lifecycle
+func (c *Client) Destroy() error {
+	return c.lifecycle(context.Background(), "destroy", nil)
+}
+
+// SyncMode returns the sync mode of the served datastore, in fast mode when
+// priming, empty if the server doesn't know it
+func (c *Client) SyncMode(fast bool) (string, error) {
+	q := url.Values{}
+	if fast {
+		q.Set("fast", "1")
+	}
+	resp, err := c.do(context.Background(), http.MethodGet, "/v0/sync-mode", q, nil)
+	if err != nil {
+		return "", err
+	}
+	defer drain(resp)
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if err := checkStatus(resp); err != nil {
+		return "", err
+	}
+	mode, err := ioutil.ReadAll(resp.Body)
+	return string(mode), err
+}
+
+func (c *Client) lifecycle(ctx context.Context, op string, q url.Values) error {
+	resp, err := c.do(ctx, http.MethodPost, "/v0/"+op, q, nil)
+	if err != nil {
+		return err
+	}
+	defer drain(resp)
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkStatus(resp)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, q url.Values, body io.Reader) (*http.Response, error) {
+	u := c.url + path
+	if len(q) != 0 {
+		u += "?" + q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	return c.http.Do(req)
+}
+
+// drain reads the rest of the body, so the connection can be reused
+func drain(resp *http.Response) {
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+func checkStatus(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return ds.ErrNotFound
+	default:
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("remote: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+}
+
+// Datastore is a ds.Batching backed by a remote server
+type Datastore struct {
+	c *Client
+}
+
+var _ ds.Batching = (*Datastore)(nil)
+
+func keyQuery(key ds.Key) url.Values {
+	return url.Values{"key": {key.String()}}
+}
+
+func (d *Datastore) Get(ctx context.Context, key ds.Key) ([]byte, error) {
+	resp, err := d.c.do(ctx, http.MethodGet, "/v0/ds", keyQuery(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer drain(resp)
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (d *Datastore) GetSize(ctx context.Context, key ds.Key) (int, error) {
+	resp, err := d.c.do(ctx, http.MethodHead, "/v0/ds", keyQuery(key), nil)
+	if err != nil {
+		return -1, err
+	}
+	defer drain(resp)
+
+	if err := checkStatus(resp); err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(resp.Header.Get("X-Size"))
+}
+
+func (d *Datastore) Has(ctx context.Context, key ds.Key) (bool, error) {
+	_, err := d.GetSize(ctx, key)
+	if err == ds.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (d *Datastore) Put(ctx context.Context, key ds.Key, value []byte) error {
+	resp, err := d.c.do(ctx, http.MethodPut, "/v0/ds", keyQuery(key), bytes.NewReader(value))
+	if err != nil {
+		return err
+	}
+	defer drain(resp)
+	return checkStatus(resp)
+}
+
+func (d *Datastore) Delete(ctx context.Context, key ds.Key) error {
+	resp, err := d.c.do(ctx, http.MethodDelete, "/v0/ds", keyQuery(key), nil)
+	if err != nil {
+		return err
+	}
+	defer drain(resp)
+	return checkStatus(resp)
+}
+
+func (d *Datastore) Sync(ctx context.Context, prefix ds.Key) error {
+	resp, err := d.c.do(ctx, http.MethodPost, "/v0/sync", url.Values{"prefix": {prefix.String()}}, nil)
+	if err != nil {
+		return err
+	}
+	defer drain(resp)
+	return checkStatus(resp)
+}
+
+func (d *Datastore) Query(ctx context.Context, q dsq.Query) (dsq.Results, error) {
+	params := url.Values{"prefix": {q.Prefix}}
+	if q.KeysOnly {
+		params.Set("keysonly", "1")
+	}
+
+	resp, err := d.c.do(ctx, http.MethodGet, "/v0/query", params, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer drain(resp)
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	var entries []dsq.Entry
+	br := bufio.NewReader(resp.Body)
+	for {
+		key, err := readField(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := binary.ReadVarint(br)
+		if err != nil {
+			return nil, err
+		}
+
+		e := dsq.Entry{Key: string(key), Size: int(size)}
+		if !q.KeysOnly {
+			if e.Value, err = readField(br); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, e)
+	}
+
+	return dsq.NaiveQueryApply(q, dsq.ResultsWithEntries(q, entries)), nil
+}
+
+// Close closes the served datastore
+func (d *Datastore) Close() error {
+	return d.c.lifecycle(context.Background(), "close", nil)
+}
+
+func (d *Datastore) Batch(ctx context.Context) (ds.Batch, error) {
+	return &batch{d: d}, nil
+}
+
+// batch buffers encoded operations, sending them on commit
+type batch struct {
+	d   *Datastore
+	buf bytes.Buffer
+}
+
+func (b *batch) add(op byte, key ds.Key, value []byte) error {
+	b.buf.WriteByte(op)
+	if err := writeField(&b.buf, []byte(key.String())); err != nil {
+		return err
+	}
+	if op == opPut {
+		return writeField(&b.buf, value)
+	}
+	return nil
+}
+
+func (b *batch) Put(ctx context.Context, key ds.Key, value []byte) error {
+	return b.add(opPut, key, value)
+}
+
+func (b *batch) Delete(ctx context.Context, key ds.Key) error {
+	return b.add(opDelete, key, nil)
+}
+
+func (b *batch) Commit(ctx context.Context) error {
+	resp, err := b.d.c.do(ctx, http.MethodPost, "/v0/batch", nil, bytes.NewReader(b.buf.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer drain(resp)
+
+	b.buf.Reset()
+	return checkStatus(resp)
+}
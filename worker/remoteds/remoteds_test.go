@@ -0,0 +1,116 @@
+package remoteds
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	dstest "github.com/ipfs/go-datastore/test"
+)
+
+func TestSuite(t *testing.T) {
+	srv := httptest.NewServer(NewServer(Static(ds.NewMapDatastore())))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	if err := c.Create(); err != nil {
+		t.Fatal(err)
+	}
+	d, err := c.Open(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SubtestCombinations is skipped, it takes long over http and query
+	// options are applied by NaiveQueryApply
+	for _, f := range []func(*testing.T, ds.Datastore){
+		dstest.SubtestBasicPutGet,
+		dstest.SubtestNotFounds,
+		dstest.SubtestPrefix,
+		dstest.SubtestOrder,
+		dstest.SubtestLimit,
+		dstest.SubtestFilter,
+		dstest.SubtestManyKeysAndQuery,
+		dstest.SubtestReturnSizes,
+		dstest.SubtestBasicSync,
+	} {
+		f(t, d)
+	}
+	for _, f := range dstest.BatchSubtests {
+		f(t, d)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	var opened []bool
+	destroyed := false
+	mds := ds.NewMapDatastore()
+	backend := Backend{
+		Create: func() (func(bool) (ds.Batching, io.Closer, error), error) {
+			return func(fast bool) (ds.Batching, io.Closer, error) {
+				opened = append(opened, fast)
+				return mds, mds, nil
+			}, nil
+		},
+		Destroy: func() {
+			destroyed = true
+		},
+		SyncMode: func(fast bool) string {
+			if fast {
+				return "none"
+			}
+			return "per-op"
+		},
+	}
+
+	srv := httptest.NewServer(NewServer(backend))
+	defer srv.Close()
+	c := NewClient(srv.URL)
+
+	for fast, expected := range map[bool]string{true: "none", false: "per-op"} {
+		if mode, err := c.SyncMode(fast); err != nil || mode != expected {
+			t.Errorf("SyncMode(%t) = %q, %v, expected %q", fast, mode, err, expected)
+		}
+	}
+
+	if _, err := c.Open(true); err == nil {
+		t.Fatal("expected error opening before create")
+	}
+	if err := c.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := c.Open(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Put(ctx, ds.NewKey("a"), []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Get(ctx, ds.NewKey("a")); err == nil {
+		t.Fatal("expected error using closed datastore")
+	}
+
+	if d, err = c.Open(false); err != nil {
+		t.Fatal(err)
+	}
+	if size, err := d.GetSize(ctx, ds.NewKey("a")); err != nil || size != 1 {
+		t.Fatalf("unexpected size %d, %v", size, err)
+	}
+	if err := c.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(opened) != 2 || !opened[0] || opened[1] {
+		t.Errorf("unexpected opens: %v", opened)
+	}
+	if !destroyed {
+		t.Error("backend not destroyed")
+	}
+}
@@ -0,0 +1,372 @@
+// Package remoteds serves a datastore over a small HTTP protocol, and
+// implements ds.Batching on top of it, so benchmarks can measure datastores
+// behind a network hop.
+//
+// Data endpoints take the datastore key in the key query param:
+//
+//	GET    /v0/ds?key=    value, 404 when not found
+//	HEAD   /v0/ds?key=    value size in X-Size, 404 when not found
+//	PUT    /v0/ds?key=    value in request body
+//	DELETE /v0/ds?key=
+//	POST   /v0/batch      batch of put and delete records in request body
+//	POST   /v0/sync?prefix=
+//	GET    /v0/query?prefix=&keysonly=1
+//
+// Lifecycle endpoints mirror worker.CandidateDatastore, servers not managing
+// the datastore lifecycle answer them with 404:
+//
+//	POST   /v0/create
+//	POST   /v0/open?fast=1
+//	POST   /v0/close
+//	POST   /v0/destroy
+//	GET    /v0/sync-mode?fast=1   sync mode of the served datastore, 404 if unknown
+package remoteds
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+)
+
+// Backend is the datastore lifecycle exposed by a server, fields are like in
+// worker.CandidateDatastore
+type Backend struct {
+	Create   func() (func(fast bool) (ds.Batching, io.Closer, error), error)
+	Destroy  func()
+	SyncMode func(fast bool) string // optional
+}
+
+// Static returns a backend always serving d, Destroy is a no-op
+func Static(d ds.Batching) Backend {
+	return Backend{
+		Create: func() (func(bool) (ds.Batching, io.Closer, error), error) {
+			return func(bool) (ds.Batching, io.Closer, error) {
+				return d, d, nil
+			}, nil
+		},
+		Destroy: func() {},
+	}
+}
+
+var errNotOpen = errors.New("datastore not open")
+
+// Server serves a backend over HTTP
+type Server struct {
+	backend Backend
+	mux     *http.ServeMux
+
+	lk     sync.RWMutex
+	open   func(bool) (ds.Batching, io.Closer, error)
+	d      ds.Batching
+	closer io.Closer
+}
+
+var _ http.Handler = (*Server)(nil)
+
+func NewServer(b Backend) *Server {
+	s := &Server{
+		backend: b,
+		mux:     http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/v0/ds", s.handleDs)
+	s.mux.HandleFunc("/v0/batch", s.handleBatch)
+	s.mux.HandleFunc("/v0/sync", s.handleSync)
+	s.mux.HandleFunc("/v0/query", s.handleQuery)
+
+	s.mux.HandleFunc("/v0/create", s.lifecycle(s.create))
+	s.mux.HandleFunc("/v0/open", s.lifecycle(func(r *http.Request) error {
+		return s.reopen(r.URL.Query().Get("fast") == "1")
+	}))
+	s.mux.HandleFunc("/v0/close", s.lifecycle(func(*http.Request) error {
+		return s.close()
+	}))
+	s.mux.HandleFunc("/v0/destroy", s.lifecycle(func(*http.Request) error {
+		err := s.close()
+		s.backend.Destroy()
+		return err
+	}))
+	s.mux.HandleFunc("/v0/sync-mode", s.handleSyncMode)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close closes the served datastore
+func (s *Server) Close() error {
+	return s.close()
+}
+
+func (s *Server) create(*http.Request) error {
+	open, err := s.backend.Create()
+	if err != nil {
+		return err
+	}
+
+	s.lk.Lock()
+	s.open = open
+	s.lk.Unlock()
+	return nil
+}
+
+func (s *Server) reopen(fast bool) error {
+	if err := s.close(); err != nil {
+		return err
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if s.open == nil {
+		return errors.New("datastore not created")
+	}
+
+	d, closer, err := s.open(fast)
+	if err != nil {
+		return err
+	}
+	s.d, s.closer = d, closer
+	return nil
+}
+
+func (s *Server) close() error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if s.closer == nil {
+		return nil
+	}
+	err := s.closer.Close()
+	s.d, s.closer = nil, nil
+	return err
+}
+
+func (s *Server) handleSyncMode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.backend.SyncMode == nil {
+		http.NotFound(w, r)
+		return
+	}
+	io.WriteString(w, s.backend.SyncMode(r.URL.Query().Get("fast") == "1"))
+}
+
+func (s *Server) lifecycle(f func(*http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := f(r); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// store returns the open datastore, with the read lock held
+func (s *Server) store(w http.ResponseWriter) (ds.Batching, bool) {
+	s.lk.RLock()
+	if s.d == nil {
+		s.lk.RUnlock()
+		http.Error(w, errNotOpen.Error(), http.StatusConflict)
+		return nil, false
+	}
+	return s.d, true
+}
+
+func writeErr(w http.ResponseWriter, err error) {
+	if err == ds.ErrNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func (s *Server) handleDs(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.store(w)
+	if !ok {
+		return
+	}
+	defer s.lk.RUnlock()
+
+	ctx := r.Context()
+	key := ds.NewKey(r.URL.Query().Get("key"))
+
+	switch r.Method {
+	case http.MethodGet:
+		v, err := d.Get(ctx, key)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(v)))
+		w.Write(v)
+	case http.MethodHead:
+		size, err := d.GetSize(ctx, key)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		w.Header().Set("X-Size", strconv.Itoa(size))
+	case http.MethodPut:
+		v, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := d.Put(ctx, key, v); err != nil {
+			writeErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if err := d.Delete(ctx, key); err != nil {
+			writeErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	d, ok := s.store(w)
+	if !ok {
+		return
+	}
+	defer s.lk.RUnlock()
+
+	ctx := r.Context()
+	b, err := d.Batch(ctx)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	br := bufio.NewReader(r.Body)
+	for {
+		op, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		key, err := readField(br)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch op {
+		case opPut:
+			var v []byte
+			if v, err = readField(br); err == nil {
+				err = b.Put(ctx, ds.NewKey(string(key)), v)
+			}
+		case opDelete:
+			err = b.Delete(ctx, ds.NewKey(string(key)))
+		default:
+			http.Error(w, "unknown batch op", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+	}
+
+	if err := b.Commit(ctx); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleSync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	d, ok := s.store(w)
+	if !ok {
+		return
+	}
+	defer s.lk.RUnlock()
+
+	if err := d.Sync(r.Context(), ds.NewKey(r.URL.Query().Get("prefix"))); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleQuery streams entries under prefix, other query parts are applied by
+// the client
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.store(w)
+	if !ok {
+		return
+	}
+	defer s.lk.RUnlock()
+
+	q := dsq.Query{
+		Prefix:   r.URL.Query().Get("prefix"),
+		KeysOnly: r.URL.Query().Get("keysonly") == "1",
+	}
+	res, err := d.Query(r.Context(), q)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	defer res.Close()
+
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	for e := range res.Next() {
+		if e.Error != nil {
+			// the status is already sent, cut the stream short
+			panic(http.ErrAbortHandler)
+		}
+
+		size := e.Size
+		if !q.KeysOnly {
+			size = len(e.Value)
+		}
+		if err := writeField(bw, []byte(e.Key)); err != nil {
+			return
+		}
+		if err := writeInt(bw, int64(size)); err != nil {
+			return
+		}
+		if !q.KeysOnly {
+			if err := writeField(bw, e.Value); err != nil {
+				return
+			}
+		}
+	}
+}
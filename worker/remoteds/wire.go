@@ -0,0 +1,48 @@
+package remoteds
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Batches and query results are sent as sequences of records made of
+// uvarint-length-prefixed fields and varints.
+
+const (
+	opPut    = 'p'
+	opDelete = 'd'
+)
+
+func writeField(w io.Writer, b []byte) error {
+	var lb [binary.MaxVarintLen64]byte
+	if _, err := w.Write(lb[:binary.PutUvarint(lb[:], uint64(len(b)))]); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func writeInt(w io.Writer, n int64) error {
+	var lb [binary.MaxVarintLen64]byte
+	_, err := w.Write(lb[:binary.PutVarint(lb[:], n)])
+	return err
+}
+
+// maxField limits field sizes read from the network
+const maxField = 1 << 30
+
+func readField(r *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxField {
+		return nil, fmt.Errorf("field too large: %d", n)
+	}
+
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
@@ -0,0 +1,163 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-ds-bench/options"
+	"github.com/ipfs/go-ds-bench/worker/helpers"
+)
+
+// dirtyReady is printed by Dirty once it's safe to kill it
+const dirtyReady = "dirty"
+
+// reuseDir makes datastores created from params open the same data from all
+// processes
+func reuseDir(params map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(params)+1)
+	for k, v := range params {
+		out[k] = v
+	}
+	out["ReuseDir"] = true
+	return out
+}
+
+// RunReopenBench measures opening a primed datastore and reading a record
+// from it. In unclean mode, before every reopen a child process (see Dirty)
+// opens the datastore, writes BatchSize records without syncing and gets
+// killed, so the datastore has to recover. Unclean mode needs candidates
+// taking the ReuseDir param, others are reported as unsupported.
+func RunReopenBench(b *testing.B, spec options.WorkerDatastore, opt options.BenchOptions, unclean bool) {
+	if unclean {
+		spec.Params = reuseDir(spec.Params)
+	}
+	store := CandidateDs(spec)
+	printSyncMode(store)
+
+	b.Run(opt.TestDesc(), func(b *testing.B) {
+		if unclean && !hasParam(spec.Type, "ReuseDir") {
+			helpers.ReportConfig("unsupported", "unclean-reopen")
+			b.Skipf("%s can't be opened from other processes", spec.Type)
+		}
+
+		ks, err := helpers.NewKeySpace(opt)
+		if err != nil {
+			b.Fatal(err)
+		}
+		dist, err := helpers.NewKeyDist(opt, opt.PrimeRecordCount)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		newStore, err := store.Create()
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer store.Destroy()
+
+		s, closer, err := newStore(true)
+		if err != nil {
+			b.Fatal(err)
+		}
+		PrimeDS(b, s, opt)
+		closer.Close()
+		syscall.Sync()
+
+		ctx := context.Background()
+		b.ResetTimer()
+		b.StopTimer()
+
+		for i := 0; i < b.N; i++ {
+			if unclean {
+				if err := runDirty(); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.StartTimer()
+			s, closer, err := newStore(false)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := s.Get(ctx, ks.Key(dist.Next())); err != nil && err != ds.ErrNotFound {
+				b.Fatal(err)
+			}
+			b.StopTimer()
+
+			if err := closer.Close(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	helpers.FlushConfig(os.Stdout)
+}
+
+// runDirty runs Dirty in a child process and kills it once it's done writing
+func runDirty() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(exe, "-dirty")
+	cmd.Stderr = os.Stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	line, err := bufio.NewReader(out).ReadString('\n')
+	cmd.Process.Kill()
+	cmd.Wait()
+
+	if err != nil || line != dirtyReady+"\n" {
+		return fmt.Errorf("dirty child failed: %q, %v", line, err)
+	}
+	return nil
+}
+
+// Dirty opens the datastore from spec in place, writes BatchSize records after
+// the primed ones without syncing, and blocks until killed
+func Dirty(spec options.TestSpec) error {
+	spec.Datastore.Params = reuseDir(spec.Datastore.Params)
+	opt := spec.Options
+
+	ks, err := helpers.NewKeySpace(opt)
+	if err != nil {
+		return err
+	}
+	vg, err := helpers.NewValueGen(opt)
+	if err != nil {
+		return err
+	}
+
+	newStore, err := CandidateDs(spec.Datastore).Create()
+	if err != nil {
+		return err
+	}
+	// priming mode, so writes aren't synced
+	s, _, err := newStore(true)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for i := 0; i < opt.BatchSize; i++ {
+		if err := s.Put(ctx, ks.Key(opt.PrimeRecordCount+i), vg.Value(opt.RecordSize)); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println(dirtyReady)
+	select {}
+}
@@ -1,17 +1,52 @@
 package worker
 
 import (
+	"fmt"
+	"os"
 	"syscall"
 	"testing"
 
 	"github.com/ipfs/go-ds-bench/options"
+	"github.com/ipfs/go-ds-bench/worker/helpers"
 
 	ds "github.com/ipfs/go-datastore"
 )
 
 type BenchFunc func(b *testing.B, store ds.Batching, opt options.BenchOptions)
 
+// printSyncMode prints sync modes as benchfmt configuration lines, master
+// keeps them with the result
+func printSyncMode(store CandidateDatastore) {
+	if store.SyncMode != nil && store.SyncMode(false) != "" {
+		fmt.Printf("sync-mode-prime: %s\n", store.SyncMode(true))
+		fmt.Printf("sync-mode: %s\n", store.SyncMode(false))
+	}
+}
+
+type TxnBenchFunc func(b *testing.B, store ds.TxnDatastore, opt options.BenchOptions)
+
+// RunTxnBench runs bf against candidates implementing ds.TxnDatastore, others
+// are reported as unsupported without priming them
+func RunTxnBench(b *testing.B, bf TxnBenchFunc, store CandidateDatastore, opt options.BenchOptions) {
+	runBench(b, func(b *testing.B, s ds.Batching, opt options.BenchOptions) {
+		bf(b, s.(ds.TxnDatastore), opt)
+	}, store, opt, func(s ds.Batching) string {
+		if _, ok := s.(ds.TxnDatastore); !ok {
+			return "txn"
+		}
+		return ""
+	})
+}
+
 func RunBench(b *testing.B, bf BenchFunc, store CandidateDatastore, opt options.BenchOptions) {
+	runBench(b, bf, store, opt, nil)
+}
+
+// runBench runs bf, unsupported returns the missing feature of a datastore
+// the benchmark can't run against
+func runBench(b *testing.B, bf BenchFunc, store CandidateDatastore, opt options.BenchOptions, unsupported func(ds.Batching) string) {
+	printSyncMode(store)
+
 	b.Run(opt.TestDesc(), func(b *testing.B) {
 		newStore, err := store.Create()
 		if err != nil {
@@ -22,7 +57,18 @@ func RunBench(b *testing.B, bf BenchFunc, store CandidateDatastore, opt options.
 		if err != nil {
 			b.Fatal(err)
 		}
-		PrimeDS(b, s, opt.PrimeRecordCount, opt.RecordSize)
+		if unsupported != nil {
+			if feature := unsupported(s); feature != "" {
+				closer.Close()
+				store.Destroy()
+
+				// master records the point as unsupported instead of failing
+				helpers.ReportConfig("unsupported", feature)
+				b.Skipf("datastore doesn't support %s", feature)
+			}
+		}
+
+		PrimeDS(b, s, opt)
 		closer.Close()
 		syscall.Sync()
 
@@ -46,4 +92,6 @@ func RunBench(b *testing.B, bf BenchFunc, store CandidateDatastore, opt options.
 		closer.Close()
 		store.Destroy()
 	})
+
+	helpers.FlushConfig(os.Stdout)
 }
@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/ipfs/go-ds-bench/options"
+	"github.com/ipfs/go-ds-bench/worker/remoteds"
+)
+
+// Serve serves the datastore described by spec over http on addr, for the
+// remote candidate, until interrupted. The served datastore is destroyed on
+// exit.
+func Serve(addr string, spec options.WorkerDatastore) error {
+	c := CandidateDs(spec)
+	s := remoteds.NewServer(remoteds.Backend{
+		Create:   c.Create,
+		Destroy:  c.Destroy,
+		SyncMode: c.SyncMode,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	srv := &http.Server{Addr: addr, Handler: s}
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+
+	log.Printf("serving %s on %s", spec.Name, addr)
+	err := srv.ListenAndServe()
+	if err == http.ErrServerClosed {
+		err = nil
+	}
+
+	s.Close()
+	c.Destroy()
+	return err
+}
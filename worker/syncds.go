@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"context"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-ds-bench/options"
+)
+
+// syncingDs provides sync modes a backend doesn't offer natively by calling
+// Sync after writes. Only usable with backends whose Sync makes previous
+// writes durable.
+type syncingDs struct {
+	ds.Batching
+	perOp bool
+}
+
+// withSync wraps d to sync in mode, d must not sync writes itself
+func withSync(d ds.Batching, mode string) ds.Batching {
+	if mode == options.SyncNone {
+		return d
+	}
+	sd := &syncingDs{
+		Batching: d,
+		perOp:    mode == options.SyncPerOp,
+	}
+	if td, ok := d.(ds.TxnDatastore); ok {
+		return &syncingTxnDs{syncingDs: sd, txn: td}
+	}
+	return sd
+}
+
+// batchSync returns the part of mode withSync provides for backends which
+// only sync per-op natively
+func batchSync(mode string) string {
+	if mode == options.SyncPerBatch {
+		return options.SyncPerBatch
+	}
+	return options.SyncNone
+}
+
+func (d *syncingDs) Put(ctx context.Context, key ds.Key, value []byte) error {
+	if err := d.Batching.Put(ctx, key, value); err != nil {
+		return err
+	}
+	if d.perOp {
+		return d.Sync(ctx, key)
+	}
+	return nil
+}
+
+func (d *syncingDs) Delete(ctx context.Context, key ds.Key) error {
+	if err := d.Batching.Delete(ctx, key); err != nil {
+		return err
+	}
+	if d.perOp {
+		return d.Sync(ctx, key)
+	}
+	return nil
+}
+
+func (d *syncingDs) Batch(ctx context.Context) (ds.Batch, error) {
+	b, err := d.Batching.Batch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &syncingBatch{Batch: b, d: d.Batching}, nil
+}
+
+type syncingBatch struct {
+	ds.Batch
+	d ds.Batching
+}
+
+func (b *syncingBatch) Commit(ctx context.Context) error {
+	if err := b.Batch.Commit(ctx); err != nil {
+		return err
+	}
+	return b.d.Sync(ctx, ds.NewKey("/"))
+}
+
+// syncingTxnDs keeps transactions of wrapped datastores available, commits
+// are synced like batch commits
+type syncingTxnDs struct {
+	*syncingDs
+	txn ds.TxnDatastore
+}
+
+func (d *syncingTxnDs) NewTransaction(ctx context.Context, readOnly bool) (ds.Txn, error) {
+	t, err := d.txn.NewTransaction(ctx, readOnly)
+	if err != nil || readOnly {
+		return t, err
+	}
+	return &syncingTxn{Txn: t, d: d.Batching}, nil
+}
+
+type syncingTxn struct {
+	ds.Txn
+	d ds.Batching
+}
+
+func (t *syncingTxn) Commit(ctx context.Context) error {
+	if err := t.Txn.Commit(ctx); err != nil {
+		return err
+	}
+	return t.d.Sync(ctx, ds.NewKey("/"))
+}
@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-ds-bench/options"
+	leveldb "github.com/ipfs/go-ds-leveldb"
+)
+
+type syncCounter struct {
+	*ds.MapDatastore
+	syncs int
+}
+
+func (c *syncCounter) Sync(ctx context.Context, prefix ds.Key) error {
+	c.syncs++
+	return nil
+}
+
+func TestWithSync(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		mode                 string
+		putSyncs, batchSyncs int
+	}{
+		{options.SyncNone, 0, 0},
+		{options.SyncPerOp, 1, 1},
+		{options.SyncPerBatch, 0, 1},
+	}
+
+	for _, c := range cases {
+		counter := &syncCounter{MapDatastore: ds.NewMapDatastore()}
+		d := withSync(counter, c.mode)
+
+		if err := d.Put(ctx, ds.NewKey("a"), []byte("a")); err != nil {
+			t.Fatal(err)
+		}
+		if counter.syncs != c.putSyncs {
+			t.Errorf("%s: %d syncs after put, expected %d", c.mode, counter.syncs, c.putSyncs)
+		}
+
+		counter.syncs = 0
+		b, err := d.Batch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Put(ctx, ds.NewKey("b"), []byte("b")); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if counter.syncs != c.batchSyncs {
+			t.Errorf("%s: %d syncs after batch commit, expected %d", c.mode, counter.syncs, c.batchSyncs)
+		}
+	}
+}
+
+type txnSyncCounter struct {
+	*leveldb.Datastore
+	syncs int
+}
+
+func (c *txnSyncCounter) Sync(ctx context.Context, prefix ds.Key) error {
+	c.syncs++
+	return nil
+}
+
+func TestWithSyncTxn(t *testing.T) {
+	ctx := context.Background()
+
+	ldb, err := leveldb.NewDatastore("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ldb.Close()
+
+	counter := &txnSyncCounter{Datastore: ldb}
+	td, ok := withSync(counter, options.SyncPerBatch).(ds.TxnDatastore)
+	if !ok {
+		t.Fatal("withSync hides transactions")
+	}
+
+	txn, err := td.NewTransaction(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Put(ctx, ds.NewKey("a"), []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if counter.syncs != 1 {
+		t.Errorf("%d syncs after txn commit, expected 1", counter.syncs)
+	}
+
+	if _, err := ldb.Get(ctx, ds.NewKey("a")); err != nil {
+		t.Fatal(err)
+	}
+}
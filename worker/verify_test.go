@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/ipfs/go-ds-bench/options"
+	"github.com/ipfs/go-ds-bench/worker/benches/basic"
+	"github.com/ipfs/go-ds-bench/worker/helpers"
+)
+
+// corrupting flips a bit of every value read
+type corrupting struct {
+	ds.Batching
+}
+
+func (c corrupting) Get(ctx context.Context, key ds.Key) ([]byte, error) {
+	v, err := c.Batching.Get(ctx, key)
+	if err == nil && len(v) > 0 {
+		v = append([]byte(nil), v...)
+		v[0] ^= 1
+	}
+	return v, err
+}
+
+func TestVerifyGet(t *testing.T) {
+	bt := flag.Lookup("test.benchtime")
+	defer bt.Value.Set(bt.Value.String())
+	bt.Value.Set("50x")
+	defer helpers.FlushConfig(ioutil.Discard)
+
+	for _, mode := range []string{options.VerifyAfter, options.VerifyOnRead} {
+		opt := options.BenchOptions{PrimeRecordCount: 100, RecordSize: 64, BatchSize: 1, Verify: mode}
+
+		for _, corrupt := range []bool{false, true} {
+			res := testing.Benchmark(func(b *testing.B) {
+				var d ds.Batching = dssync.MutexWrap(ds.NewMapDatastore())
+				PrimeDS(b, d, opt)
+				if corrupt {
+					d = corrupting{d}
+				}
+				basic.BenchGet(b, d, opt)
+			})
+
+			if failed := res.N == 0; failed != corrupt {
+				t.Errorf("%s: corrupt %t, failed %t", mode, corrupt, failed)
+			}
+		}
+	}
+}
+
+// losing loses every record, Has and GetSize included
+type losing struct {
+	ds.Batching
+}
+
+func (losing) Get(context.Context, ds.Key) ([]byte, error)  { return nil, ds.ErrNotFound }
+func (losing) Has(context.Context, ds.Key) (bool, error)    { return false, nil }
+func (losing) GetSize(context.Context, ds.Key) (int, error) { return -1, ds.ErrNotFound }
+
+// TestLostRecords checks lookups fail on present keys reported missing, also
+// when not verifying
+func TestLostRecords(t *testing.T) {
+	bt := flag.Lookup("test.benchtime")
+	defer bt.Value.Set(bt.Value.String())
+	bt.Value.Set("50x")
+
+	opt := options.BenchOptions{PrimeRecordCount: 4, RecordSize: 64, BatchSize: 1}
+	for name, bench := range map[string]BenchFunc{"get": basic.BenchGet, "getsize": basic.BenchGetSize, "has": basic.BenchHas} {
+		for _, lose := range []bool{false, true} {
+			res := testing.Benchmark(func(b *testing.B) {
+				var d ds.Batching = dssync.MutexWrap(ds.NewMapDatastore())
+				PrimeDS(b, d, opt)
+				if lose {
+					d = losing{d}
+				}
+				bench(b, d, opt)
+			})
+
+			if failed := res.N == 0; failed != lose {
+				t.Errorf("%s: lose %t, failed %t", name, lose, failed)
+			}
+		}
+	}
+}
@@ -2,32 +2,122 @@ package worker
 
 import (
 	"encoding/json"
-	"github.com/ipfs/go-ds-bench/options"
-	"github.com/ipfs/go-ds-bench/worker/benches/basic"
+	"flag"
+	"fmt"
 	"io/ioutil"
+	"os"
 	"testing"
+
+	"github.com/ipfs/go-ds-bench/options"
+	"github.com/ipfs/go-ds-bench/worker/benches/basic"
+	"github.com/ipfs/go-ds-bench/worker/benches/txn"
 )
 
-func BenchmarkSpec(b *testing.B) {
-	j, err := ioutil.ReadFile("spec.json")
-	if err != nil {
-		b.Fatal(err)
+var serve = flag.String("serve", "", "serve the datastore from spec.json over http on this address, for the remote candidate")
+var dirty = flag.Bool("dirty", false, "write to the datastore from spec.json and wait to be killed, used by unclean reopen benchmarks")
+var crash = flag.Int("crash", -1, "write records from this index on to the datastore from spec.json until killed, used by crash benchmarks")
+var listCandidates = flag.Bool("list-candidates", false, "print datastores and wrappers available in this binary and their params as json and exit")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if *serve != "" {
+		if err := serveSpec(*serve); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if *dirty {
+		spec, err := readSpec()
+		if err == nil {
+			err = Dirty(spec)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *crash >= 0 {
+		spec, err := readSpec()
+		if err == nil {
+			err = Crash(spec, *crash)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *listCandidates {
+		if err := json.NewEncoder(os.Stdout).Encode(options.WorkerInfo{
+			Datastores: CandidateInfos(),
+			Wrappers:   WrapperInfos(),
+		}); err != nil {
+			panic(err)
+		}
+		os.Exit(0)
 	}
 
+	os.Exit(m.Run())
+}
+
+// readSpec reads spec.json, applying DsParam to datastore params
+func readSpec() (options.TestSpec, error) {
 	var spec options.TestSpec
+
+	j, err := ioutil.ReadFile("spec.json")
+	if err != nil {
+		return spec, err
+	}
 	if err := json.Unmarshal(j, &spec); err != nil {
+		return spec, err
+	}
+	spec.Datastore.Params = spec.Options.DatastoreParams(spec.Datastore.Params)
+	return spec, nil
+}
+
+// serveSpec serves the datastore from spec.json
+func serveSpec(addr string) error {
+	spec, err := readSpec()
+	if err != nil {
+		return err
+	}
+	return Serve(addr, spec.Datastore)
+}
+
+func BenchmarkSpec(b *testing.B) {
+	spec, err := readSpec()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := flatfsKeys(spec.Datastore, spec.Options); err != nil {
 		b.Fatal(err)
 	}
 
 	switch spec.Test {
 	case "get":
 		RunBench(b, basic.BenchGet, CandidateDs(spec.Datastore), spec.Options)
+	case "getsize":
+		RunBench(b, basic.BenchGetSize, CandidateDs(spec.Datastore), spec.Options)
 	case "has":
 		RunBench(b, basic.BenchHas, CandidateDs(spec.Datastore), spec.Options)
 	case "add":
 		RunBench(b, basic.BenchAdd, CandidateDs(spec.Datastore), spec.Options)
 	case "add-batch":
 		RunBench(b, basic.BenchAddBatch, CandidateDs(spec.Datastore), spec.Options)
+	case "update":
+		RunBench(b, basic.BenchUpdate, CandidateDs(spec.Datastore), spec.Options)
+	case "update-batch":
+		RunBench(b, basic.BenchUpdateBatch, CandidateDs(spec.Datastore), spec.Options)
+	case "txn-rmw":
+		RunTxnBench(b, txn.BenchReadModifyWrite, CandidateDs(spec.Datastore), spec.Options)
+	case "txn-put":
+		RunTxnBench(b, txn.BenchPut, CandidateDs(spec.Datastore), spec.Options)
+	case "reopen":
+		RunReopenBench(b, spec.Datastore, spec.Options, false)
+	case "reopen-unclean":
+		RunReopenBench(b, spec.Datastore, spec.Options, true)
+	case "crash":
+		RunCrashBench(b, spec.Datastore, spec.Options)
 	default:
 		b.Fatalf("unknown test '%s'", spec.Test)
 	}
@@ -0,0 +1,197 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/autobatch"
+	"github.com/ipfs/go-datastore/keytransform"
+	"github.com/ipfs/go-datastore/mount"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/ipfs/go-ds-bench/options"
+	"github.com/ipfs/go-ds-bench/worker/remoteds"
+	measure "github.com/ipfs/go-ds-measure"
+)
+
+func init() {
+	RegisterWrapper("mount", WrapperMount, mountParams{Prefix: "/"})
+	RegisterWrapper("keytransform", WrapperKeytransform, keytransformParams{Prefix: "/wrapped"})
+	RegisterWrapper("autobatch", WrapperAutobatch, autobatchParams{Size: 16})
+	RegisterWrapper("mutex", WrapperMutex, nil)
+	RegisterWrapper("measure", WrapperMeasure, measureParams{Prefix: "bench"})
+	RegisterWrapper("remote", WrapperRemote, nil)
+}
+
+type mountParams struct {
+	Prefix      string `doc:"prefix the datastore is mounted at, removed from keys passed to it; benchmark keys must fall under it, see KeyPrefix"`
+	ExtraMounts int    `doc:"number of empty in-memory datastores mounted next to it"`
+}
+
+var WrapperMount = func(spec options.WrapperSpec) (func(ds.Batching) (ds.Batching, error), error) {
+	p := mountParams{Prefix: "/"}
+	if err := options.DecodeParams(spec.Params, &p); err != nil {
+		return nil, err
+	}
+
+	return func(d ds.Batching) (ds.Batching, error) {
+		mounts := []mount.Mount{{Prefix: ds.NewKey(p.Prefix), Datastore: d}}
+		for i := 0; i < p.ExtraMounts; i++ {
+			mounts = append(mounts, mount.Mount{
+				Prefix:    ds.NewKey(fmt.Sprintf("/mount-%d", i)),
+				Datastore: ds.NewMapDatastore(),
+			})
+		}
+		return mount.New(mounts), nil
+	}, nil
+}
+
+type keytransformParams struct {
+	Prefix string `doc:"prefix added to keys, note that flatfs doesn't accept nested keys"`
+}
+
+var WrapperKeytransform = func(spec options.WrapperSpec) (func(ds.Batching) (ds.Batching, error), error) {
+	p := keytransformParams{Prefix: "/wrapped"}
+	if err := options.DecodeParams(spec.Params, &p); err != nil {
+		return nil, err
+	}
+
+	return func(d ds.Batching) (ds.Batching, error) {
+		return keytransform.Wrap(d, keytransform.PrefixTransform{Prefix: ds.NewKey(p.Prefix)}), nil
+	}, nil
+}
+
+type autobatchParams struct {
+	Size int `doc:"number of buffered writes triggering a flush"`
+}
+
+// autobatching exposes autobatch as ds.Batching, explicit batches bypass the
+// write buffer
+type autobatching struct {
+	*autobatch.Datastore
+	child ds.Batching
+}
+
+func (a *autobatching) Batch(ctx context.Context) (ds.Batch, error) {
+	// flush first, so batched writes don't get overwritten by buffered ones
+	if err := a.Flush(ctx); err != nil {
+		return nil, err
+	}
+	return a.child.Batch(ctx)
+}
+
+var WrapperAutobatch = func(spec options.WrapperSpec) (func(ds.Batching) (ds.Batching, error), error) {
+	p := autobatchParams{Size: 16}
+	if err := options.DecodeParams(spec.Params, &p); err != nil {
+		return nil, err
+	}
+	if p.Size < 1 {
+		return nil, fmt.Errorf("autobatch Size must be positive, got %d", p.Size)
+	}
+
+	return func(d ds.Batching) (ds.Batching, error) {
+		return &autobatching{
+			Datastore: autobatch.NewAutoBatching(d, p.Size),
+			child:     d,
+		}, nil
+	}, nil
+}
+
+var WrapperMutex = func(spec options.WrapperSpec) (func(ds.Batching) (ds.Batching, error), error) {
+	if err := options.DecodeParams(spec.Params, &struct{}{}); err != nil {
+		return nil, err
+	}
+
+	return func(d ds.Batching) (ds.Batching, error) {
+		return dssync.MutexWrap(d), nil
+	}, nil
+}
+
+type measureParams struct {
+	Prefix string `doc:"metrics prefix"`
+}
+
+var WrapperMeasure = func(spec options.WrapperSpec) (func(ds.Batching) (ds.Batching, error), error) {
+	p := measureParams{Prefix: "bench"}
+	if err := options.DecodeParams(spec.Params, &p); err != nil {
+		return nil, err
+	}
+
+	return func(d ds.Batching) (ds.Batching, error) {
+		return measure.New(p.Prefix, d), nil
+	}, nil
+}
+
+// loopbackDs is a datastore served over loopback http
+type loopbackDs struct {
+	*remoteds.Datastore
+	srv *http.Server
+}
+
+func (d *loopbackDs) Close() error {
+	err := d.Datastore.Close()
+	d.srv.Close()
+	return err
+}
+
+// WrapperRemote puts a local http hop in front of the datastore, to compare
+// with the remote candidate or measure protocol overhead
+var WrapperRemote = func(spec options.WrapperSpec) (func(ds.Batching) (ds.Batching, error), error) {
+	if err := options.DecodeParams(spec.Params, &struct{}{}); err != nil {
+		return nil, err
+	}
+
+	return func(d ds.Batching) (ds.Batching, error) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, err
+		}
+
+		srv := &http.Server{Handler: remoteds.NewServer(remoteds.Static(d))}
+		go srv.Serve(l)
+
+		c := remoteds.NewClient("http://" + l.Addr().String())
+		if err := c.Create(); err != nil {
+			srv.Close()
+			return nil, err
+		}
+		rd, err := c.Open(false)
+		if err != nil {
+			srv.Close()
+			return nil, err
+		}
+		return &loopbackDs{Datastore: rd, srv: srv}, nil
+	}, nil
+}
+
+// wrapChain validates wrapper specs, returning a function layering them over
+// a datastore in order
+func wrapChain(specs []options.WrapperSpec) (func(ds.Batching) (ds.Batching, error), error) {
+	wraps := make([]func(ds.Batching) (ds.Batching, error), len(specs))
+	for i, spec := range specs {
+		w, ok := wrapper(spec.Type)
+		if !ok {
+			return nil, fmt.Errorf("unknown wrapper: '%s', available: %s", spec.Type, strings.Join(Wrappers(), ", "))
+		}
+
+		var err error
+		wraps[i], err = w(spec)
+		if err != nil {
+			return nil, fmt.Errorf("wrapper %d (%s): %w", i, spec.Type, err)
+		}
+	}
+
+	return func(d ds.Batching) (ds.Batching, error) {
+		for i, wrap := range wraps {
+			var err error
+			d, err = wrap(d)
+			if err != nil {
+				return nil, fmt.Errorf("wrapper %d (%s): %w", i, specs[i].Type, err)
+			}
+		}
+		return d, nil
+	}, nil
+}